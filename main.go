@@ -3,11 +3,18 @@ package main
 import (
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/gcode"
+	"42clients.com/puzzlebox/pkg/hpgl"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+	"42clients.com/puzzlebox/pkg/toolpath"
 
 	"github.com/ajstarks/svgo"
 	"github.com/charmbracelet/log"
@@ -15,7 +22,24 @@ import (
 
 var redDotted = "stroke:red;stroke-width:1;stroke-dasharray:5,1;fill:none"
 var blueSolid = "stroke:blue;stroke-width:1;fill:none"
-var greenSolid = "stroke:green;stroke-width:1;fill:none"
+var greyThin = "stroke:grey;stroke-width:0.5;fill:none"
+var purpleDashed = "stroke:purple;stroke-width:1;stroke-dasharray:2,2;fill:none"
+var labelStyle = "fill:grey;font-family:sans-serif;font-size:6px"
+
+// kindStyles picks the SVG style for each kind of line
+var kindStyles = map[box.LineKind]string{
+	box.Cut:         blueSolid,
+	box.Crease:      redDotted,
+	box.Annotation:  greyThin,
+	box.Perforation: purpleDashed,
+}
+
+func strokeStyle(s toolpath.Stroke) string {
+	return kindStyles[s.Kind]
+}
+
+// dielineGap separates the pieces placed on one sheet
+const dielineGap = 20
 
 func main() {
 	logger := log.NewWithOptions(os.Stderr, log.Options{
@@ -33,17 +57,210 @@ func main() {
 	depth := flag.Float64("depth", 206, "Box depth (sides) in mm")
 	height := flag.Float64("height", 196, "Box height in mm")
 	foldGap := flag.Float64("gap", 2, "Gap for folds in mm")
+	bottomTab := flag.Float64("bottom-tab", 0.6, "Crash-lock flap depth as a fraction of the box depth")
+	kerf := flag.Float64("kerf", 0, "Cutter kerf in mm, cut lines are offset by half of it")
+	thickness := flag.Float64("thickness", 0.5, "Board thickness in mm")
+	jointTabs := flag.Int("joint-tabs", 0, "Close the tube with this many tabs and slots instead of the glue tab")
+
+	// Box style
+	styleName := flag.String("style", "tuck", "Box style: "+strings.Join(box.StyleNames(), " or "))
+	thumbNotch := flag.Float64("thumb-notch", 12, "Hinged lid: thumb notch radius in mm, 0 for none")
+	magnets := flag.String("magnets", "", "Hinged lid: magnet positions across the front as fractions, e.g. 0.25,0.75")
+	magnetDiameter := flag.Float64("magnet-d", 8, "Hinged lid: magnet recess diameter in mm")
+	pull := flag.String("pull", "notch", "Drawer: pull the drawer by a notch or a ribbon")
+	pullSize := flag.Float64("pull-size", 8, "Drawer: pull notch radius or ribbon width in mm")
+	hiddenLock := flag.Bool("hidden-lock", false, "Drawer: add a hidden lock released by pressing the sleeve")
+	lidDepth := flag.Float64("lid-depth", 0, "Telescoping: how far the lid comes down in mm, 0 for a third of the height")
+
+	// Windows
+	var windowSpecs []string
+	flag.Func("window", "Add a window, repeatable: panel:shape:x,y,width,height[,radius] with the centre from the panel's top left,\n"+
+		"or panel:path:x,y:<svg path> (panels: front, back, sidea, sideb, lid; shapes: rect, rounded, circle, ellipse)",
+		func(spec string) error {
+			windowSpecs = append(windowSpecs, spec)
+			return nil
+		})
+	windowFilm := flag.Float64("window-film", 0, "Mark a film glue margin this wide round each window in mm")
+
+	// Artwork
+	var artworkSpecs []string
+	flag.Func("artwork", "Place shapes from an SVG file, repeatable: panel:kind:x,y[,width,height]:<file.svg> with the centre\n"+
+		"from the panel's top left, scaled to fit width by height if given (kinds: cut, hole, crease, perf, note).\n"+
+		"Any other file holds path data moved by x,y whose numbers may be expressions like {D*0.5} or {H - 2*FoldGap}",
+		func(spec string) error {
+			artworkSpecs = append(artworkSpecs, spec)
+			return nil
+		})
+
+	// Hanger
+	hanger := flag.String("hanger", "", "Add a header card above the back of the box with a euro or round hole.\n"+
+		"The hinged style can't take one, its lid hinges where the header would stand")
+	hangerHeight := flag.Float64("hanger-height", 40, "Height of the hanger header in mm")
+	hangerX := flag.Float64("hanger-x", 0, "Move the hanger hole sideways from the middle in mm")
+
+	// Insert
+	grid := flag.String("grid", "", "Add dividers making rows x columns compartments, e.g. 2x3")
+	gridRows := flag.String("grid-rows", "", "Compartment depths front to back in mm, e.g. 40,60, instead of equal rows")
+	gridColumns := flag.String("grid-columns", "", "Compartment widths left to right in mm instead of equal columns")
+	tray := flag.Bool("tray", false, "Add a folded tray insert that holds the dividers")
+
+	// Glue tab
+	glueSide := flag.String("glue-side", "back", "Panel carrying the glue tab: back or side")
+	glueTaper := flag.Float64("glue-taper", 15, "Glue tab taper angle at both ends in degrees")
+	glueArea := flag.Bool("glue-area", false, "Show the hatched glue area on the glue tab")
+
+	// Machine output
+	writeGCode := flag.Bool("gcode", false, "Also write a G-code program (.nc) for knife and creasing wheel")
+	gcodeHeader := flag.String("gcode-header", "", "File with lines to use as the G-code header")
+	gcodeFooter := flag.String("gcode-footer", "", "File with lines to use as the G-code footer")
+	writeHPGL := flag.Bool("hpgl", false, "Also write an HPGL job (.plt) with cut and crease pens")
+
+	// Cleanup
+	cleanup := flag.Bool("cleanup", true, "Merge straight runs and drop lines that a cut or an earlier line already covers")
+
+	// Job report
+	report := flag.Bool("report", false, "Print cut, crease and perforation lengths per dieline with the estimated machine time")
+	machine := toolpath.DefaultProfile()
+	cutSpeed := flag.Float64("cut-speed", machine.Speeds[box.Cut], "Report: cutting speed in mm/s")
+	creaseSpeed := flag.Float64("crease-speed", machine.Speeds[box.Crease], "Report: creasing speed in mm/s")
+	perfSpeed := flag.Float64("perf-speed", machine.Speeds[box.Perforation], "Report: perforating speed in mm/s")
+	flag.Float64Var(&machine.TravelSpeed, "travel-speed", machine.TravelSpeed, "Report: tool-up travel speed in mm/s")
+	flag.Float64Var(&machine.Acceleration, "acceleration", machine.Acceleration, "Report: machine acceleration in mm/s²")
+	flag.Float64Var(&machine.PierceTime, "pierce-time", machine.PierceTime, "Report: time to lower the tool at the start of each stroke in s")
 
 	flag.Parse()
 
 	// Create box
 	myBox := box.NewBox(*width, *depth, *height, *foldGap)
+	myBox.Kerf = *kerf
+	myBox.BottomTabPercent = *bottomTab
+	myBox.Thickness = *thickness
+	myBox.GlueTab.TaperTop = *glueTaper
+	myBox.GlueTab.TaperBottom = *glueTaper
+	myBox.GlueTab.ShowGlueArea = *glueArea
+	switch *glueSide {
+	case "back":
+		myBox.GlueTab.Panel = box.GlueOnBack
+	case "side":
+		myBox.GlueTab.Panel = box.GlueOnSideA
+	default:
+		logger.Error("Unknown glue tab side", "glue-side", *glueSide)
+		os.Exit(1)
+	}
+	// The joint takes the glue tab's place on the seam
+	if *jointTabs > 0 {
+		myBox.Joints = append(myBox.Joints, box.Joint{
+			Edge: myBox.SeamEdge(),
+			Tabs: *jointTabs,
+		})
+	}
+
+	for _, spec := range windowSpecs {
+		w, err := parseWindow(spec)
+		if err != nil {
+			logger.Error("Invalid window", "window", spec, "error", err)
+			os.Exit(1)
+		}
+		w.FilmMargin = *windowFilm
+		myBox.Windows = append(myBox.Windows, w)
+	}
+
+	for _, spec := range artworkSpecs {
+		a, err := parseArtwork(spec)
+		if err != nil {
+			logger.Error("Invalid artwork", "artwork", spec, "error", err)
+			os.Exit(1)
+		}
+		myBox.Artwork = append(myBox.Artwork, a)
+	}
+
+	if *hanger != "" {
+		h := box.DefaultHanger()
+		h.Height = *hangerHeight
+		h.HoleX = *hangerX
+		switch *hanger {
+		case "euro":
+			h.Hole = box.EuroSlot
+		case "round":
+			h.Hole = box.RoundHole
+		default:
+			logger.Error("Unknown hanger hole", "hanger", *hanger)
+			os.Exit(1)
+		}
+		myBox.Hanger = &h
+	}
 
 	if !myBox.IsValid() {
 		logger.Error("Invalid box dimensions", "width", *width, "depth", *depth, "height", *height)
 		os.Exit(1)
 	}
 
+	style, ok := box.StyleByName(*styleName)
+	if !ok {
+		logger.Error("Unknown box style", "style", *styleName)
+		os.Exit(1)
+	}
+	if hinged, ok := style.(box.HingedLid); ok {
+		hinged.ThumbNotchRadius = *thumbNotch
+		hinged.MagnetDiameter = *magnetDiameter
+		positions, err := parseList(*magnets)
+		if err != nil {
+			logger.Error("Invalid magnet positions", "magnets", *magnets, "error", err)
+			os.Exit(1)
+		}
+		hinged.Magnets = positions
+		style = hinged
+	}
+	if drawer, ok := style.(box.SleeveDrawer); ok {
+		switch *pull {
+		case "notch":
+			drawer.Pull = box.PullNotch
+		case "ribbon":
+			drawer.Pull = box.RibbonSlot
+		default:
+			logger.Error("Unknown drawer pull", "pull", *pull)
+			os.Exit(1)
+		}
+		drawer.PullSize = *pullSize
+		drawer.HiddenLock = *hiddenLock
+		style = drawer
+	}
+	if telescoping, ok := style.(box.Telescoping); ok {
+		telescoping.LidDepth = *lidDepth
+		style = telescoping
+	}
+
+	if err := style.Validate(*myBox); err != nil {
+		logger.Error("Box cannot be made", "style", style.Name(), "error", err)
+		os.Exit(1)
+	}
+
+	// An insert is wanted when any of its flags is set
+	var insert *box.Grid
+	if *grid != "" || *gridRows != "" || *gridColumns != "" || *tray {
+		g := box.Grid{Rows: 1, Columns: 1, Tray: *tray}
+		if *grid != "" {
+			if _, err := fmt.Sscanf(*grid, "%dx%d", &g.Rows, &g.Columns); err != nil {
+				logger.Error("Invalid grid, expected rows x columns", "grid", *grid, "error", err)
+				os.Exit(1)
+			}
+		}
+		var err error
+		if g.RowSizes, err = parseList(*gridRows); err != nil {
+			logger.Error("Invalid grid rows", "grid-rows", *gridRows, "error", err)
+			os.Exit(1)
+		}
+		if g.ColumnSizes, err = parseList(*gridColumns); err != nil {
+			logger.Error("Invalid grid columns", "grid-columns", *gridColumns, "error", err)
+			os.Exit(1)
+		}
+		if err := myBox.ValidateGrid(g); err != nil {
+			logger.Error("Insert does not fit", "error", err)
+			os.Exit(1)
+		}
+		insert = &g
+	}
+
 	// Determine filename
 	var filename string
 	if *outputFile != "" {
@@ -76,13 +293,41 @@ func main() {
 	}
 	defer f.Close()
 
-	// Generate all paths
-	paths := myBox.GenerateCompleteBox()
+	// Generate all paths and put them in cutting order
+	dielines, err := style.Generate(*myBox)
+	if err != nil {
+		logger.Error("Error generating box", "style", style.Name(), "error", err)
+		return
+	}
+	if insert != nil {
+		piece, err := myBox.GenerateInsert(*insert)
+		if err != nil {
+			logger.Error("Error generating insert", "error", err)
+			return
+		}
+		dielines = append(dielines, piece)
+	}
+	sheet, err := box.Arrange(dielines, dielineGap)
+	if err != nil {
+		logger.Error("Error laying out the sheet", "error", err)
+		return
+	}
+	strokes, err := toolpath.FromLayers(sheet.Layers)
+	if err != nil {
+		logger.Error("Error reading generated paths", "error", err)
+		return
+	}
+	var changes toolpath.Report
+	if *cleanup {
+		strokes, changes = toolpath.Clean(strokes)
+	}
+	plan := toolpath.Order(strokes, pathbuilder.Point{})
+	logger.Info("Ordered toolpath", "strokes", len(plan.Strokes), "travel", fmt.Sprintf("%.1f mm", plan.Travel))
 
 	// SVG canvas dimensions (add padding)
 	padding := 20
-	canvasWidth := myBox.TotalWidth() + padding*2
-	canvasHeight := myBox.TotalHeight() + padding*2
+	canvasWidth := int(math.Ceil(sheet.Width)) + padding*2
+	canvasHeight := int(math.Ceil(sheet.Height)) + padding*2
 
 	// Start SVG
 	canvas := svg.New(f)
@@ -97,18 +342,62 @@ func main() {
 	// Transform the group for proper positioning
 	canvas.Group(fmt.Sprintf("transform=\"translate(%d, %d)\"", padding, padding))
 
-	logger.Info("Added fold lines")
-	canvas.Path(paths["fold_lines"], redDotted)
-
-	logger.Info("Added cut lines")
-	canvas.Path(paths["cut_lines"], blueSolid)
+	// Label each piece when there is more than one
+	if len(sheet.Pieces) > 1 {
+		for _, piece := range sheet.Pieces {
+			canvas.Text(int(piece.X), int(piece.Y)-2, piece.Name, labelStyle)
+		}
+	}
 
-	logger.Info("Add a rectangle")
-	canvas.Path(paths["rect"], greenSolid)
+	// Annotations go underneath, then paths in cutting order so the SVG can
+	// drive a plotter directly
+	for _, stroke := range strokes {
+		if stroke.Kind == box.Annotation {
+			canvas.Path(stroke.Path.String(), strokeStyle(stroke))
+		}
+	}
+	for _, stroke := range plan.Strokes {
+		canvas.Path(stroke.Path.String(), strokeStyle(stroke))
+	}
+	logger.Info("Added paths", "strokes", len(plan.Strokes))
 
 	canvas.Gend() // End transform group
 	canvas.End()
 
+	if *writeGCode {
+		cfg := gcode.DefaultConfig()
+		cfg.SheetHeight = sheet.Height
+		if *gcodeHeader != "" {
+			if cfg.Header, err = readLines(*gcodeHeader); err != nil {
+				logger.Error("Error reading G-code header", "file", *gcodeHeader, "error", err)
+				return
+			}
+		}
+		if *gcodeFooter != "" {
+			if cfg.Footer, err = readLines(*gcodeFooter); err != nil {
+				logger.Error("Error reading G-code footer", "file", *gcodeFooter, "error", err)
+				return
+			}
+		}
+		ncFile := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".nc"
+		if err := writeProgram(ncFile, plan, cfg); err != nil {
+			logger.Error("Error writing G-code", "filename", ncFile, "error", err)
+			return
+		}
+		logger.Info("G-code generated", "file", ncFile)
+	}
+
+	if *writeHPGL {
+		cfg := hpgl.DefaultConfig()
+		cfg.SheetHeight = sheet.Height
+		pltFile := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".plt"
+		if err := writePlot(pltFile, plan, cfg); err != nil {
+			logger.Error("Error writing HPGL", "filename", pltFile, "error", err)
+			return
+		}
+		logger.Info("HPGL generated", "file", pltFile)
+	}
+
 	logger.Info("Box generated:",
 		"file", filename,
 		"size", fmt.Sprintf("%dx%d px", canvasWidth, canvasHeight))
@@ -117,5 +406,189 @@ func main() {
 	fmt.Printf("Box generated:\n")
 	fmt.Printf("  File: %s\n", filename)
 	fmt.Printf("  Dimensions: %.0f×%.0f×%.0f mm\n", *width, *depth, *height)
+	fmt.Printf("  Travel: %.1f mm\n", plan.Travel)
+	if changes.Changed() {
+		printChanges(changes)
+	}
+
+	if *report {
+		machine.Speeds = map[box.LineKind]float64{
+			box.Cut:         *cutSpeed,
+			box.Crease:      *creaseSpeed,
+			box.Perforation: *perfSpeed,
+		}
+		if err := printReport(dielines, plan, machine, *cleanup); err != nil {
+			logger.Error("Error measuring paths", "error", err)
+			return
+		}
+	}
+}
+
+// printReport lists the machined length of each kind per dieline with how
+// long the machine takes on it alone, then the same for the whole sheet
+func printReport(dielines []box.Dieline, plan toolpath.Plan, profile toolpath.Profile, cleanup bool) error {
+	line := func(name string, lengths map[box.LineKind]float64, estimate time.Duration) {
+		fmt.Printf("  %s: cut %.1f mm, crease %.1f mm, perforation %.1f mm, about %s\n", name,
+			lengths[box.Cut], lengths[box.Crease], lengths[box.Perforation], estimate.Round(time.Second))
+	}
+
+	fmt.Printf("Job report:\n")
+	for _, d := range dielines {
+		strokes, err := toolpath.FromLayers(d.Layers)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+		if cleanup {
+			strokes, _ = toolpath.Clean(strokes)
+		}
+		line(d.Name, toolpath.Lengths(strokes), toolpath.Order(strokes, pathbuilder.Point{}).Estimate(pathbuilder.Point{}, profile))
+	}
+	line("Sheet", toolpath.Lengths(plan.Strokes), plan.Estimate(pathbuilder.Point{}, profile))
+	return nil
+}
+
+// printChanges lists what the cleanup stage did to the generated paths
+func printChanges(r toolpath.Report) {
+	fmt.Printf("Cleanup:\n")
+	if r.Empty > 0 {
+		fmt.Printf("  Removed %d zero-length segments\n", r.Empty)
+	}
+	if r.Merged > 0 {
+		fmt.Printf("  Merged %d segments into straight runs\n", r.Merged)
+	}
+	for _, o := range r.Overlaps {
+		fmt.Printf("  %s: dropped %.1f mm of %s under %s %s\n", o.Layer, o.Length, o.Kind, o.UnderKind, o.Under)
+	}
+}
+
+// writeProgram writes the ordered plan as a G-code program
+func writeProgram(filename string, plan toolpath.Plan, cfg gcode.Config) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gcode.Write(f, plan, cfg)
+}
+
+// writePlot writes the ordered plan as an HPGL job
+func writePlot(filename string, plan toolpath.Plan, cfg hpgl.Config) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return hpgl.Write(f, plan, cfg)
+}
+
+// panelNames are the panels as named on the command line
+var panelNames = map[string]box.Panel{"sidea": box.SideA, "front": box.Front, "sideb": box.SideB, "back": box.Back, "lid": box.Lid}
 
+// parseWindow reads a -window flag
+func parseWindow(spec string) (box.Window, error) {
+	fields := strings.SplitN(spec, ":", 4)
+	if len(fields) < 3 {
+		return box.Window{}, fmt.Errorf("expected panel:shape:numbers")
+	}
+	shapes := map[string]box.WindowShape{"rect": box.WindowRect, "rounded": box.WindowRoundedRect,
+		"circle": box.WindowCircle, "ellipse": box.WindowEllipse, "path": box.WindowPath}
+
+	var w box.Window
+	var ok bool
+	if w.Panel, ok = panelNames[fields[0]]; !ok {
+		return w, fmt.Errorf("unknown panel %q", fields[0])
+	}
+	if w.Shape, ok = shapes[fields[1]]; !ok {
+		return w, fmt.Errorf("unknown shape %q", fields[1])
+	}
+	numbers, err := parseList(fields[2])
+	if err != nil {
+		return w, err
+	}
+	if len(numbers) < 2 {
+		return w, fmt.Errorf("window needs a position")
+	}
+	w.X, w.Y = numbers[0], numbers[1]
+	if w.Shape == box.WindowPath {
+		if len(fields) < 4 {
+			return w, fmt.Errorf("path window needs path data")
+		}
+		w.Path = fields[3]
+		return w, nil
+	}
+	sizes := append(numbers[2:], 0, 0, 0)
+	w.Width, w.Height, w.Radius = sizes[0], sizes[1], sizes[2]
+	return w, nil
+}
+
+// parseArtwork reads an -artwork flag and loads its SVG file
+func parseArtwork(spec string) (box.Artwork, error) {
+	fields := strings.SplitN(spec, ":", 4)
+	if len(fields) < 4 {
+		return box.Artwork{}, fmt.Errorf("expected panel:kind:numbers:file")
+	}
+	kinds := map[string]box.LineKind{"cut": box.Cut, "hole": box.Cut, "crease": box.Crease,
+		"perf": box.Perforation, "note": box.Annotation}
+
+	var a box.Artwork
+	var ok bool
+	if a.Panel, ok = panelNames[fields[0]]; !ok {
+		return a, fmt.Errorf("unknown panel %q", fields[0])
+	}
+	if a.Kind, ok = kinds[fields[1]]; !ok {
+		return a, fmt.Errorf("unknown kind %q", fields[1])
+	}
+	a.Hole = fields[1] == "hole"
+	numbers, err := parseList(fields[2])
+	if err != nil {
+		return a, err
+	}
+	if len(numbers) != 2 && len(numbers) != 4 {
+		return a, fmt.Errorf("artwork needs a position and optionally a width and height")
+	}
+	a.X, a.Y = numbers[0], numbers[1]
+	if len(numbers) == 4 {
+		a.Width, a.Height = numbers[2], numbers[3]
+	}
+
+	// Other files hold path data whose numbers may be {expressions}
+	if !strings.EqualFold(filepath.Ext(fields[3]), ".svg") {
+		data, err := os.ReadFile(fields[3])
+		if err != nil {
+			return a, err
+		}
+		a.Template = strings.TrimSpace(string(data))
+		return a, nil
+	}
+	f, err := os.Open(fields[3])
+	if err != nil {
+		return a, err
+	}
+	defer f.Close()
+	if a.Path, err = pathbuilder.ParseSVG(f); err != nil {
+		return a, fmt.Errorf("%s: %w", fields[3], err)
+	}
+	return a, nil
+}
+
+// parseList reads comma separated numbers; an empty string gives none
+func parseList(s string) ([]float64, error) {
+	var values []float64
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' }) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// readLines loads a header or footer file, one G-code line per line
+func readLines(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n"), nil
 }
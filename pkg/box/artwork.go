@@ -0,0 +1,139 @@
+package box
+
+import (
+	"fmt"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Artwork is a shape drawn in another program, such as a custom flap or
+// cutout from Inkscape, placed on a panel. X and Y place the centre of the
+// shape in the panel's frame, see PanelFrame.
+type Artwork struct {
+	Panel Panel
+	Path  pathbuilder.Path
+	// Template is used instead of Path when set: path data whose numbers
+	// may be expressions of the box's Measurements and the panel's
+	// PanelWidth and PanelHeight in braces, e.g. "M0,0 L{D*0.5},{H/2}". It
+	// is evaluated whenever the artwork is generated, so it follows the
+	// box's dimensions. The shape is drawn in the panel's own coordinates
+	// and moved by X, Y rather than centred, and its expressions size it.
+	Template string
+	// Kind says how the shape is machined. Annotations are drawn for the
+	// person assembling the box.
+	Kind LineKind
+	// Hole cuts the shape out of the board, so kerf compensation shrinks
+	// the opening instead of growing the outline
+	Hole bool
+	X, Y float64
+	// Width and Height scale the shape, keeping its proportions, to fit a
+	// box this size. Zero for both keeps the size it was drawn at.
+	Width  float64
+	Height float64
+}
+
+// artworkLayers names the layer each kind of artwork is drawn on
+var artworkLayers = map[LineKind]string{
+	Cut:         "cut_artwork",
+	Crease:      "fold_artwork",
+	Perforation: "perf_artwork",
+	Annotation:  "note_artwork",
+}
+
+// artworkShape returns the artwork in its panel's frame
+func (b Box) artworkShape(a Artwork) (pathbuilder.Path, error) {
+	if a.Template != "" {
+		vars := b.Measurements()
+		f := b.PanelFrame(a.Panel)
+		vars["PanelWidth"], vars["PanelHeight"] = f.Width, f.Height
+		path, err := pathbuilder.EvalPath(a.Template, vars)
+		if err != nil {
+			return pathbuilder.Path{}, err
+		}
+		return path.Translate(a.X, a.Y), nil
+	}
+	if a.Width > 0 && a.Height > 0 {
+		return a.Path.Fit(a.Width, a.Height).Translate(a.X-a.Width/2, a.Y-a.Height/2), nil
+	}
+	minX, minY, maxX, maxY := a.Path.Bounds()
+	return a.Path.Translate(a.X-(minX+maxX)/2, a.Y-(minY+maxY)/2), nil
+}
+
+// ValidateArtwork checks the artwork has something to draw and a usable
+// size
+func (b Box) ValidateArtwork(a Artwork) error {
+	shape, err := b.artworkShape(a)
+	if err != nil {
+		return fmt.Errorf("artwork on the %s: %w", a.Panel, err)
+	}
+	if len(shape.Subpaths) == 0 {
+		return fmt.Errorf("artwork on the %s is empty", a.Panel)
+	}
+	if a.Template != "" && (a.Width != 0 || a.Height != 0) {
+		return fmt.Errorf("artwork on the %s is sized by its template, not a width and height", a.Panel)
+	}
+	if (a.Width > 0) != (a.Height > 0) || a.Width < 0 || a.Height < 0 {
+		return fmt.Errorf("artwork on the %s needs both a width and a height to be scaled", a.Panel)
+	}
+	if _, ok := artworkLayers[a.Kind]; !ok {
+		return fmt.Errorf("artwork on the %s has unknown line kind %s", a.Panel, a.Kind)
+	}
+	if a.Hole && a.Kind != Cut {
+		return fmt.Errorf("artwork on the %s can only be a hole when it is cut", a.Panel)
+	}
+	return nil
+}
+
+// GenerateArtwork returns the artwork by layer name. Closed cut shapes that
+// overlap are merged with a union so the knife follows only their combined
+// outline; open cuts, creases and the rest are drawn as they are.
+func (b Box) GenerateArtwork() map[string]string {
+	layers := map[string]pathbuilder.Path{}
+	var outlines, holes pathbuilder.Path
+	for _, a := range b.Artwork {
+		local, err := b.artworkShape(a)
+		if err != nil {
+			continue
+		}
+		shape := b.PanelFrame(a.Panel).Path(local)
+		if a.Kind != Cut {
+			name := artworkLayers[a.Kind]
+			layer := layers[name]
+			layer.Subpaths = append(layer.Subpaths, shape.Subpaths...)
+			layers[name] = layer
+			continue
+		}
+		closed := pathbuilder.Path{FillRule: shape.FillRule}
+		open := layers["cut_artwork"]
+		for _, sp := range shape.Subpaths {
+			if sp.IsClosed() {
+				closed.Subpaths = append(closed.Subpaths, sp)
+			} else {
+				open.Subpaths = append(open.Subpaths, sp)
+			}
+		}
+		if len(open.Subpaths) > 0 {
+			layers["cut_artwork"] = open
+		}
+		if len(closed.Subpaths) == 0 {
+			continue
+		}
+		if a.Hole {
+			holes = holes.Union(closed)
+		} else {
+			outlines = outlines.Union(closed)
+		}
+	}
+
+	paths := map[string]string{}
+	for name, layer := range layers {
+		paths[name] = layer.String()
+	}
+	if len(outlines.Subpaths) > 0 {
+		paths["cut_artwork"] += outlines.String()
+	}
+	if len(holes.Subpaths) > 0 {
+		paths["cut_hole_artwork"] = holes.String()
+	}
+	return paths
+}
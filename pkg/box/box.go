@@ -1,34 +1,107 @@
 package box
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"42clients.com/puzzlebox/pkg/pathbuilder"
 )
 
+// LineKind tells the cutter what to do with a path
+type LineKind int
+
+const (
+	Cut LineKind = iota
+	Crease
+	// Annotation marks guides for the person assembling the box; they are
+	// drawn but never machined.
+	Annotation
+	// Perforation is cut in short dashes so the board tears cleanly along
+	// it, e.g. for a tear strip or a tear-off header.
+	Perforation
+)
+
+func (k LineKind) String() string {
+	switch k {
+	case Crease:
+		return "crease"
+	case Annotation:
+		return "annotation"
+	case Perforation:
+		return "perforation"
+	default:
+		return "cut"
+	}
+}
+
+// LayerKind maps a path name from GenerateCompleteBox to its line kind.
+// Layers are named by kind first, e.g. "fold_lines", "cut_lines" or
+// "perf_tear_strip" or "note_glue_area"; anything unrecognised is treated as
+// a cut.
+func LayerKind(name string) LineKind {
+	prefix, _, _ := strings.Cut(name, "_")
+	switch prefix {
+	case "fold", "crease":
+		return Crease
+	case "note":
+		return Annotation
+	case "perf":
+		return Perforation
+	default:
+		return Cut
+	}
+}
+
 // Box represents dimensions as floats to simplify calculations.
 // Internal calculations return floats.
 // Public calculations output integers for ease of use by external drawing commands which expect ints.
 type Box struct {
-	Width            float64
-	Depth            float64
-	Height           float64
-	FoldGap          float64
+	Width   float64
+	Depth   float64
+	Height  float64
+	FoldGap float64
+	// BottomTabPercent is the depth of the main bottom flaps as a fraction
+	// of Depth. The crash-lock hooks only pass the centre above 0.5.
 	BottomTabPercent float64
+	// Kerf is the width of material removed by the cutter. Cut lines are
+	// moved out by half of it so the finished part keeps nominal size.
+	Kerf float64
+	// Thickness of the board, used to size slots and clearances
+	Thickness float64
+	GlueTab   GlueTab
+	// Joints lock edges together with tabs and slots instead of glue
+	Joints []Joint
+	// Windows are display cutouts in the panels
+	Windows []Window
+	// Artwork places shapes drawn in other programs on the panels
+	Artwork []Artwork
+	// Hanger adds a header card for peg hooks when set
+	Hanger *Hanger
+
+	// lidOnBack is set by styles that hinge the lid on the back panel
+	// instead of the front, see PanelRect
+	lidOnBack bool
 }
 
-// NewBox creates a new Box with default values
+// NewBox creates a new Box with default values. BottomTabPercent defaults
+// to 0.6, not the 0.5 of the rectangular bottom flaps the crash lock
+// replaced, since flaps that stop at the centre cannot lock.
 func NewBox(width, depth, height, foldGap float64) *Box {
 	return &Box{
 		Width:            width,
 		Depth:            depth,
 		Height:           height,
 		FoldGap:          foldGap,
-		BottomTabPercent: 0.50,
+		BottomTabPercent: 0.60,
+		Thickness:        0.5,
+		GlueTab:          DefaultGlueTab(),
 	}
 }
 
 // TotalWidth dimension calculations
 func (b Box) TotalWidth() int {
-	totalWidth := int((2.0 * b.Width) + (2.0 * b.Depth) + b.SideFlapWidth())
+	totalWidth := int((2.0 * b.Width) + (2.0 * b.Depth) + b.GlueTabWidth())
 	return totalWidth
 }
 func (b Box) W() int { return int(b.Width) }
@@ -51,9 +124,33 @@ func (b Box) TopFlapHeight() float64 {
 	return .2 * b.Depth
 }
 
+// Measurements names the box's sizes for expressions in path templates,
+// see pathbuilder.EvalPath. W, D and H are the exact width, depth and
+// height, unlike the whole millimetres of the W, D and H methods.
+func (b Box) Measurements() map[string]float64 {
+	return map[string]float64{
+		"W":                   b.Width,
+		"D":                   b.Depth,
+		"H":                   b.Height,
+		"Width":               b.Width,
+		"Depth":               b.Depth,
+		"Height":              b.Height,
+		"FoldGap":             b.FoldGap,
+		"Thickness":           b.Thickness,
+		"Kerf":                b.Kerf,
+		"SideFlapWidth":       b.SideFlapWidth(),
+		"SideFlapHeight":      b.SideFlapHeight(),
+		"TopFlapHeight":       b.TopFlapHeight(),
+		"BottomFlapMaxHeight": b.BottomFlapMaxHeight(),
+		"GlueTabWidth":        b.GlueTabWidth(),
+		"TotalWidth":          float64(b.TotalWidth()),
+		"TotalHeight":         float64(b.TotalHeight()),
+	}
+}
+
 // Position calculations
 func (b Box) BackRight() int {
-	return int((2 * b.Width) + (2 * b.Depth))
+	return int(b.panelsLeft() + (2 * b.Width) + (2 * b.Depth))
 }
 
 func (b Box) Bottom() int {
@@ -65,7 +162,7 @@ func (b Box) Top() int {
 }
 
 func (b Box) SideALeft() int {
-	return 0
+	return int(b.panelsLeft())
 }
 
 func (b Box) SideFlapHeight() float64 {
@@ -74,19 +171,19 @@ func (b Box) SideFlapHeight() float64 {
 
 // Panel positions for more complex layouts
 func (b Box) FrontPanelLeft() int {
-	return int(b.Depth)
+	return int(b.panelsLeft() + b.Depth)
 }
 
 func (b Box) FrontPanelRight() int {
-	return int(b.Depth + b.Width)
+	return int(b.panelsLeft() + b.Depth + b.Width)
 }
 
 func (b Box) BackPanelLeft() int {
-	return int(b.Depth + b.Width + b.Depth)
+	return int(b.panelsLeft() + b.Depth + b.Width + b.Depth)
 }
 
 func (b Box) BackPanelRight() int {
-	return int(b.Depth + b.Width + b.Depth + b.Width)
+	return int(b.panelsLeft() + b.Depth + b.Width + b.Depth + b.Width)
 }
 
 // PathBuilder integration for generating various box components
@@ -97,7 +194,24 @@ func (b Box) GenerateFoldLines() string {
 
 	originX := b.SideALeft()
 	originY := b.Bottom()
-	mainBoxWidth := b.TotalWidth() - int(b.SideFlapWidth())
+	mainBoxWidth := b.BackRight() - b.SideALeft()
+
+	// The vertical fold is drawn on the edge that carries the glue tab
+	if b.GlueTab.Panel == GlueOnSideA {
+		originX = b.BackRight()
+		mainBoxWidth = -mainBoxWidth
+	}
+
+	// A joint on the seam is cut with its tabs, so only the top and bottom
+	// of the panels fold
+	if _, ok := b.seamJoint(); ok {
+		return builder.
+			MoveTo(originX, originY).
+			HorizontalLine(mainBoxWidth).Square().
+			MoveTo(originX, b.Top()).
+			HorizontalLine(mainBoxWidth).Square().
+			Build().String() + b.panelFolds()
+	}
 
 	// Main box outline fold lines
 	// Bottom horizontal fold line
@@ -107,79 +221,171 @@ func (b Box) GenerateFoldLines() string {
 		VerticalLine(-1 * b.H()).Square(). // Right vertical fold line
 		HorizontalLine(-1 * mainBoxWidth).Square()
 
-	return builder.Build()
+	return builder.Build().String() + b.panelFolds()
 }
 
-// GenerateCutLines creates cut lines for side flaps using relative coordinates
-func (b Box) GenerateCutLines() string {
-	builder := pathbuilder.NewAdvancedPathBuilder()
-
-	// Left side flap - simple rectangular flap
-	leftFlapPath := builder.
-		MoveTo(0, b.Top()).
-		VerticalLine(int(b.Height)).Square().
-		HorizontalLine(int(b.Depth)).Square().
-		VerticalLine(-int(b.Height)).Square().
-		Build()
-
-	return leftFlapPath
+// panelFolds creates the vertical folds between the four panels, down the
+// left edges of the front, side B and back panels
+func (b Box) panelFolds() string {
+	var folds string
+	for _, p := range []Panel{Front, SideB, Back} {
+		f := b.PanelFrame(p)
+		folds += f.Builder().
+			MoveTo(0, 0).
+			VerticalLine(int(f.Height)).Square().
+			Build().String()
+	}
+	return folds
 }
 
-// GenerateBottomFlaps creates the bottom flaps for the box
-func (b Box) GenerateBottomFlaps() string {
-	builder := pathbuilder.NewAdvancedPathBuilder()
-
-	// Front bottom flap - simple rectangular flap
-	frontBottomPath := builder.
-		MoveTo(int(b.Depth), b.Bottom()).
-		VerticalLine(int(b.BottomFlapMaxHeight())).Square().
-		HorizontalLine(int(b.Width)).Square().
-		VerticalLine(-int(b.BottomFlapMaxHeight())).Square().
-		Build()
-
-	return frontBottomPath
+// GenerateCutLines cuts the free outer edge at the end of the panel strip
+// without the glue tab: side A's left edge, or the back panel's right edge
+// when the tab is on side A. When a joint closes the seam it is the edge
+// facing the joint's tabs, which are cut with the joint. The panels' other
+// edges are folds.
+func (b Box) GenerateCutLines() string {
+	// Drawn down side A or up the back panel so kerf compensation moves it
+	// away from the panel
+	edge := b.freeEdge()
+	f := b.PanelFrame(edge.Panel)
+	from, height := f.Anchor(TopLeft), int(f.Height)
+	if edge.Edge == RightEdge {
+		from, height = f.Anchor(BottomRight), -int(f.Height)
+	}
+	return f.Builder().
+		MoveTo(int(from.X), int(from.Y)).
+		VerticalLine(height).Square().
+		Build().String()
 }
 
 // GenerateTopFlaps creates the top flaps for the box
 func (b Box) GenerateTopFlaps() string {
-	builder := pathbuilder.NewAdvancedPathBuilder()
+	front := b.PanelFrame(Front)
+	flap := int(b.TopFlapHeight())
 
 	// Front top flap - simple rectangular flap
-	frontTopPath := builder.
-		MoveTo(int(b.Depth), b.Top()).
-		VerticalLine(-int(b.TopFlapHeight())).Square().
-		HorizontalLine(int(b.Width)).Square().
-		VerticalLine(int(b.TopFlapHeight())).Square().
-		Build()
-
-	return frontTopPath
+	return front.Builder().
+		MoveTo(0, 0).
+		VerticalLine(-flap).Square().
+		HorizontalLine(int(front.Width)).Square().
+		VerticalLine(flap).Square().
+		Build().String()
 }
 
-// GenerateSideTabs creates tabs for gluing sides
+// GenerateSideTabs creates the glue tab, tapered at both ends so it
+// doesn't jam the top and bottom folds
 func (b Box) GenerateSideTabs() string {
-	builder := pathbuilder.NewAdvancedPathBuilder()
-
-	// Right side tab - simple rectangular tab
-	rightTabPath := builder.
-		MoveTo(int(b.Depth+b.Width+b.Depth), b.Top()).
-		HorizontalLine(int(b.SideFlapWidth())).Square().
-		VerticalLine(int(b.Height)).Square().
-		HorizontalLine(-int(b.SideFlapWidth())).Square().
-		Build()
+	top, bottom := b.glueTabInsets()
+	topInset := int(top)
+	bottomInset := int(bottom)
+	tabWidth := int(b.GlueTabWidth())
+	// The tab folds off the top right corner of the back panel
+	f := b.PanelFrame(Back)
+	fold := f.Anchor(TopRight)
+	if b.GlueTab.Panel == GlueOnSideA {
+		f = b.PanelFrame(SideA)
+		fold = f.Anchor(TopLeft)
+		tabWidth = -tabWidth
+	}
 
-	return rightTabPath
+	return f.Builder().
+		MoveTo(int(fold.X), int(fold.Y)).
+		RelativeLine(tabWidth, topInset).Square().
+		VerticalLine(int(f.Height)-topInset-bottomInset).Square().
+		RelativeLine(-tabWidth, bottomInset).Square().
+		Build().String()
 }
 
 // GenerateCompleteBox creates all paths for a complete box template
-func (b Box) GenerateCompleteBox() map[string]string {
-	return map[string]string{
-		"fold_lines": b.GenerateFoldLines(),
-		"cut_lines":  b.GenerateCutLines(),
-		"rect":       pathbuilder.CreateRectangle(30, 20, 50, 30, 1),
+func (b Box) GenerateCompleteBox() (map[string]string, error) {
+	paths := b.generateTube()
+	paths["cut_tuck_closure"] = b.GenerateTuckClosureCuts()
+	paths["fold_tuck_closure"] = b.GenerateTuckClosureFolds()
+	return b.applyKerf(paths)
+}
+
+// generateTube creates the panels, bottom and seam shared by every style
+// built on the four panel tube. Callers add the top and apply the kerf.
+func (b Box) generateTube() map[string]string {
+	paths := map[string]string{
+		"fold_lines":        b.GenerateFoldLines(),
+		"cut_lines":         b.GenerateCutLines(),
+		"cut_bottom_flaps":  b.GenerateBottomFlaps(),
+		"fold_bottom_flaps": b.GenerateBottomFolds(),
+	}
+	// A joint on the seam replaces the glue tab
+	if _, ok := b.seamJoint(); !ok {
+		paths["cut_glue_tab"] = b.GenerateSideTabs()
+		if b.GlueTab.ShowGlueArea {
+			paths["note_glue_area"] = b.GenerateGlueArea()
+		}
+	}
+	for _, j := range b.Joints {
+		joint := b.GenerateJoint(j)
+		paths["cut_joint_tabs"] += joint.Tabs
+		paths["fold_joint_tabs"] += joint.Folds
+		paths["cut_hole_joint_slots"] += joint.Slots
+	}
+	if len(b.Windows) > 0 {
+		paths["cut_hole_windows"], paths["note_window_film"] = b.GenerateWindows()
+	}
+	for name, path := range b.GenerateArtwork() {
+		paths[name] = path
+	}
+	if b.Hanger != nil {
+		paths["fold_hanger"] = b.GenerateHangerFold()
+		paths["cut_hole_hanger"] = b.GenerateHangerHoles()
+	}
+	return paths
+}
+
+// joinTolerance is how close the ends of two cut strokes must be for kerf
+// compensation to treat them as one line
+const joinTolerance = 0.01
+
+// applyKerf offsets the cut layers by half the kerf. Fold lines are left
+// alone because the creasing tool doesn't remove material. Holes, named
+// "cut_hole_...", shrink instead so the opening keeps its nominal size.
+// The outline is usually drawn across several layers, so they are offset
+// together: strokes that meet end to end still meet at a mitred corner
+// afterwards rather than leaving a step the width of the kerf.
+// A layer that can't be offset is an error, since cutting it as drawn
+// would make the part the wrong size.
+func (b Box) applyKerf(paths map[string]string) (map[string]string, error) {
+	if b.Kerf <= 0 {
+		return paths, nil
+	}
+	var outlines, holes []string
+	for name := range paths {
+		switch {
+		case LayerKind(name) != Cut:
+		case strings.HasPrefix(name, "cut_hole_"):
+			holes = append(holes, name)
+		default:
+			outlines = append(outlines, name)
+		}
+	}
+	sort.Strings(outlines)
+	sort.Strings(holes)
+	for _, group := range []struct {
+		names    []string
+		distance float64
+	}{{outlines, b.Kerf / 2}, {holes, -b.Kerf / 2}} {
+		parsed := make([]pathbuilder.Path, len(group.names))
+		for i, name := range group.names {
+			var err error
+			if parsed[i], err = pathbuilder.ParsePath(paths[name]); err != nil {
+				return nil, fmt.Errorf("kerf compensation of %s: %w", name, err)
+			}
+		}
+		for i, offset := range pathbuilder.OffsetTogether(parsed, group.distance, joinTolerance) {
+			paths[group.names[i]] = offset.String()
+		}
 	}
+	return paths, nil
 }
 
 // Validation methods
 func (b Box) IsValid() bool {
-	return b.Width > 0 && b.Depth > 0 && b.Height > 0 && b.FoldGap >= 0
+	return b.Width > 0 && b.Depth > 0 && b.Height > 0 && b.FoldGap >= 0 && b.Kerf >= 0 && b.Thickness >= 0
 }
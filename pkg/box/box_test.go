@@ -0,0 +1,53 @@
+package box
+
+import (
+	"strings"
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// outlineEnds lists the open ends of the strokes in a piece's cut layers,
+// leaving out holes
+func outlineEnds(t *testing.T, d Dieline) []pathbuilder.Point {
+	t.Helper()
+	var ends []pathbuilder.Point
+	for name, data := range d.Layers {
+		if LayerKind(name) != Cut || strings.HasPrefix(name, "cut_hole_") {
+			continue
+		}
+		path, err := pathbuilder.ParsePath(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sp := range path.Subpaths {
+			if !sp.IsClosed() {
+				ends = append(ends, sp.Start, sp.End())
+			}
+		}
+	}
+	return ends
+}
+
+func TestKerfKeepsCornersClosed(t *testing.T) {
+	for _, style := range []Style{TuckEnd{}, DefaultHingedLid(), DefaultTelescoping(), DefaultSleeveDrawer()} {
+		b := *NewBox(100, 70, 120, 0)
+		b.Kerf = 0.4
+		pieces, err := style.Generate(b)
+		if err != nil {
+			t.Fatalf("%s: %v", style.Name(), err)
+		}
+		for _, d := range pieces {
+			// Ends that met before the offset still meet, whether their
+			// strokes are in the same layer or not
+			ends := outlineEnds(t, d)
+			for i := range ends {
+				for j := i + 1; j < len(ends); j++ {
+					if gap := ends[i].Dist(ends[j]); gap > 1e-6 && gap < 2*b.Kerf {
+						t.Errorf("%s %s: %.3f mm step between %v and %v", style.Name(), d.Name, gap, ends[i], ends[j])
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package box
+
+import (
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// TuckClosure holds the measurements of a tuck end closure. They are all
+// derived from Width and Depth so the closure scales with the box.
+type TuckClosure struct {
+	// TuckHeight is how far the tuck flap reaches past the top panel
+	TuckHeight float64
+	TuckRadius float64
+	// LockLength is the friction-lock slit cut along each end of the tuck
+	// fold; the shoulders it leaves catch on the dust flaps.
+	LockLength float64
+	DustHeight float64
+	DustRadius float64
+	// ReliefFront and ReliefBack lean the dust flap edges away from the
+	// neighbouring panels, in degrees, so they clear the tuck when closing.
+	ReliefFront float64
+	ReliefBack  float64
+}
+
+// TuckClosure derives the closure for the top of the front panel
+func (b Box) TuckClosure() TuckClosure {
+	tuckHeight := b.TopFlapHeight()
+	dustHeight := math.Min(0.45*b.Width, 0.8*b.Depth)
+	return TuckClosure{
+		TuckHeight:  tuckHeight,
+		TuckRadius:  math.Min(0.8*tuckHeight, b.Width/4),
+		LockLength:  math.Max(b.FoldGap, 0.02*b.Width),
+		DustHeight:  dustHeight,
+		DustRadius:  0.3 * dustHeight,
+		ReliefFront: 15,
+		ReliefBack:  5,
+	}
+}
+
+// GenerateTuckClosureCuts creates the top panel and curved tuck flap over
+// the front panel, the dust flaps over both side panels and the free edge of
+// the back panel.
+func (b Box) GenerateTuckClosureCuts() string {
+	tc := b.TuckClosure()
+	lock := int(tc.LockLength)
+	tuck := int(tc.TuckHeight)
+	radius := int(tc.TuckRadius)
+
+	// Top panel edges, friction-lock slits and tuck flap in one run
+	tuckPath := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.FrontPanelLeft(), b.Top()).
+		VerticalLine(-b.D()).Square().
+		HorizontalLine(lock).Square().
+		VerticalLine(-tuck).Rounded(radius).
+		HorizontalLine(b.W() - 2*lock).Rounded(radius).
+		VerticalLine(tuck).Square().
+		HorizontalLine(lock).Square().
+		VerticalLine(b.D()).Square().
+		Build().String()
+
+	sideA := b.dustFlap(b.SideALeft(), b.FrontPanelLeft(), tc)
+	sideB := b.dustFlap(b.FrontPanelRight()+b.D(), b.FrontPanelRight(), tc)
+
+	// Drawn right to left so kerf compensation moves it away from the panel
+	backEdge := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelRight(), b.Top()).
+		HorizontalLine(-b.W()).Square().
+		Build().String()
+	if b.Hanger != nil {
+		backEdge = b.GenerateHangerOutline()
+	}
+
+	return tuckPath + sideA + sideB + backEdge
+}
+
+// GenerateTuckClosureFolds creates the crease between the top panel and the
+// tuck flap, stopping at the friction-lock slits.
+func (b Box) GenerateTuckClosureFolds() string {
+	tc := b.TuckClosure()
+	lock := int(tc.LockLength)
+
+	return pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.FrontPanelLeft()+lock, b.Top()-b.D()).
+		HorizontalLine(b.W() - 2*lock).Square().
+		Build().String()
+}
+
+// dustFlap draws a dust flap on a side panel from its back edge to the edge
+// shared with the front panel. The front edge leans further away so the
+// flap clears the tuck.
+func (b Box) dustFlap(backX, frontX int, tc TuckClosure) string {
+	height := int(tc.DustHeight)
+	radius := int(tc.DustRadius)
+	backLean := int(tc.DustHeight * math.Tan(tc.ReliefBack*math.Pi/180))
+	frontLean := int(tc.DustHeight * math.Tan(tc.ReliefFront*math.Pi/180))
+	topLength := int(math.Abs(float64(frontX-backX))) - backLean - frontLean
+
+	// Drawn for side A, running left to right towards the front panel, from
+	// a back edge at x = 0
+	flap := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(0, b.Top()).
+		RelativeLine(backLean, -height).Rounded(radius/2).
+		HorizontalLine(topLength).Rounded(radius).
+		RelativeLine(frontLean, height).Square().
+		Build()
+	// Side B runs right to left, the same flap mirrored
+	if frontX < backX {
+		flap = flap.MirrorX(0)
+	}
+	return flap.Translate(float64(backX), 0).String()
+}
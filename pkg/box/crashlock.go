@@ -0,0 +1,143 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// minHookLength is the shortest hook, in mm, that still holds the bottom shut
+const minHookLength = 3.0
+
+// CrashLock holds the measurements of an auto-lock bottom.
+//
+// The front and back flaps are the main flaps. Each one reaches the centre
+// of the bottom and carries a hook on one half and a lock notch on the
+// other; folded from opposite sides, each hook drops into the other flap's
+// notch. The side flaps carry the diagonal glue folds that pull the main
+// flaps into place when the flattened tube is opened.
+type CrashLock struct {
+	// FlapDepth is the main flap depth from the fold to the tip of the hook
+	FlapDepth float64
+	// HookLength is how far the hook passes the centre line
+	HookLength float64
+	// The notch runs from the flap's edge past the middle by the clearance
+	// and the hook takes the rest of the width, so the other flap's hook
+	// has the clearance to spare on either side of the middle.
+	HookWidth  float64
+	NotchWidth float64
+	NotchDepth float64
+	// SideDepth is the depth of the side flaps and their glue triangles
+	SideDepth float64
+	Clearance float64
+}
+
+// CrashLock derives the bottom from Width, Depth and BottomTabPercent
+func (b Box) CrashLock() CrashLock {
+	clearance := math.Max(b.FoldGap/2, 0.5)
+	flapDepth := b.BottomFlapMaxHeight()
+	hook := flapDepth - b.Depth/2
+	return CrashLock{
+		FlapDepth:  flapDepth,
+		HookLength: hook,
+		HookWidth:  b.Width/2 - clearance,
+		NotchWidth: b.Width/2 + clearance,
+		NotchDepth: hook + clearance,
+		SideDepth:  math.Min(b.Depth/2, b.Width/2) - clearance,
+		Clearance:  clearance,
+	}
+}
+
+// ValidateCrashLock checks that the flaps interlock once folded
+func (b Box) ValidateCrashLock() error {
+	cl := b.CrashLock()
+	switch {
+	case cl.HookLength <= 0:
+		return fmt.Errorf("bottom flaps stop %.1f mm short of the centre, BottomTabPercent must be above 0.5", math.Abs(cl.HookLength))
+	case cl.HookLength < minHookLength:
+		return fmt.Errorf("hooks pass the centre by %.1f mm, at least %.1f mm is needed to lock", cl.HookLength, minHookLength)
+	case cl.NotchDepth >= b.Depth/2:
+		return fmt.Errorf("lock notch %.1f mm deep leaves nothing of a %.1f mm flap, reduce BottomTabPercent", cl.NotchDepth, b.Depth/2)
+	case cl.HookWidth <= 0:
+		return fmt.Errorf("box is too narrow for a %.1f mm lock clearance", cl.Clearance)
+	case cl.SideDepth <= 0:
+		return fmt.Errorf("side flaps have no room for glue folds")
+	}
+	return nil
+}
+
+// GenerateBottomFlaps creates the crash-lock bottom: hooked main flaps under
+// the front and back panels and glue flaps under the side panels. Where two
+// flaps meet, the side flap cuts the edge between them.
+func (b Box) GenerateBottomFlaps() string {
+	cl := b.CrashLock()
+	side := int(cl.SideDepth)
+	// The side flaps' free corners are rounded, so they run straight down
+	// the main flaps' right edges for less of their depth
+	return b.mainBottomFlap(b.FrontPanelLeft(), side, side-side/4) +
+		b.mainBottomFlap(b.BackPanelLeft(), side, 0) +
+		b.sideBottomFlap(b.SideALeft()) +
+		b.sideBottomFlap(b.FrontPanelRight())
+}
+
+// GenerateBottomFolds creates the diagonal glue folds on the side flaps.
+// Side A is glued to the front flap and side B to the back flap, so the
+// bottom is the same after turning the box round.
+func (b Box) GenerateBottomFolds() string {
+	cl := b.CrashLock()
+	side := int(cl.SideDepth)
+
+	sideA := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.FrontPanelLeft(), b.Bottom()).
+		RelativeLine(-side, side).Square().
+		Build().String()
+	sideB := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelLeft(), b.Bottom()).
+		RelativeLine(-side, side).Square().
+		Build().String()
+
+	return sideA + sideB
+}
+
+// mainBottomFlap draws a main flap with the lock notch on its left half and
+// the hook on its right half. The notch's inner edge carries straight on as
+// the hook's, so no thin strips of board are left between them. The first
+// leftShared and rightShared mm of its edges are cut with the side flaps
+// beside it.
+func (b Box) mainBottomFlap(left, leftShared, rightShared int) string {
+	cl := b.CrashLock()
+	base := int(b.Depth / 2)
+	hook := int(cl.HookLength)
+	notchDepth := int(cl.NotchDepth)
+	// Rounded up so the hook keeps its clearance on whole mm
+	notchWidth := int(math.Ceil(cl.NotchWidth))
+	radius := hook / 2
+	shoulder := base - notchDepth
+	leftShared = min(leftShared, shoulder)
+
+	builder := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(left, b.Bottom()+leftShared)
+	if shoulder > leftShared {
+		builder = builder.VerticalLine(shoulder - leftShared).Square()
+	}
+	return builder.
+		HorizontalLine(notchWidth).Square().
+		VerticalLine(notchDepth + hook).Rounded(radius).
+		HorizontalLine(b.W() - notchWidth).Rounded(radius).
+		VerticalLine(-(base + hook - rightShared)).Square().
+		Build().String()
+}
+
+// sideBottomFlap draws a side flap, its free corner rounded
+func (b Box) sideBottomFlap(left int) string {
+	cl := b.CrashLock()
+	side := int(cl.SideDepth)
+
+	return pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(left, b.Bottom()).
+		VerticalLine(side).Rounded(side / 4).
+		HorizontalLine(b.D()).Square().
+		VerticalLine(-side).Square().
+		Build().String()
+}
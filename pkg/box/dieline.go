@@ -0,0 +1,73 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Dieline is one separate piece cut from the sheet. Its layers are drawn
+// from the piece's own origin and Width and Height bound them, so several
+// pieces can be placed on one sheet with Sheet.
+type Dieline struct {
+	Name   string
+	Layers map[string]string
+	Width  float64
+	Height float64
+}
+
+// BoxDieline wraps the layers a style generated for the box itself
+func (b Box) BoxDieline(name string, layers map[string]string) Dieline {
+	return Dieline{
+		Name:   name,
+		Layers: layers,
+		Width:  float64(b.TotalWidth()),
+		Height: float64(b.TotalHeight()),
+	}
+}
+
+// labelHeight is the room left above each piece on a sheet for its name
+const labelHeight = 8
+
+// Placement is where a dieline went on the sheet
+type Placement struct {
+	Dieline
+	X, Y float64
+}
+
+// Sheet is a set of dielines laid out to be cut in one job
+type Sheet struct {
+	// Layers joins the layers of every piece, moved into place. Layers with
+	// the same name in different pieces are merged.
+	Layers map[string]string
+	Pieces []Placement
+	Width  float64
+	Height float64
+}
+
+// Arrange stacks the dielines top to bottom, gap apart. When there is more
+// than one, each sits below a line left free for its label. A layer that
+// can't be read is an error rather than a piece missing from the sheet.
+func Arrange(dielines []Dieline, gap float64) (Sheet, error) {
+	sheet := Sheet{Layers: map[string]string{}}
+	for i, d := range dielines {
+		if i > 0 {
+			sheet.Height += gap
+		}
+		if len(dielines) > 1 {
+			sheet.Height += labelHeight
+		}
+		for name, data := range d.Layers {
+			path, err := pathbuilder.ParsePath(data)
+			if err != nil {
+				return Sheet{}, fmt.Errorf("%s layer %s: %w", d.Name, name, err)
+			}
+			sheet.Layers[name] += path.Translate(0, sheet.Height).String()
+		}
+		sheet.Pieces = append(sheet.Pieces, Placement{Dieline: d, Y: sheet.Height})
+		sheet.Height += d.Height
+		sheet.Width = math.Max(sheet.Width, d.Width)
+	}
+	return sheet, nil
+}
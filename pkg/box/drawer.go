@@ -0,0 +1,263 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// DrawerPull is how the drawer is pulled out of its sleeve
+type DrawerPull int
+
+const (
+	// PullNotch is a half circle cut into the top of the front wall
+	PullNotch DrawerPull = iota
+	// RibbonSlot is a slot in the base behind the front wall for a ribbon
+	// that is glued underneath and hangs out of the front
+	RibbonSlot
+)
+
+func (p DrawerPull) String() string {
+	if p == RibbonSlot {
+		return "ribbon slot"
+	}
+	return "pull notch"
+}
+
+// SleeveDrawer is a matchbox: an open drawer with double walls sliding in
+// a sleeve. Width, Depth and Height are the drawer's outside, and it slides
+// along its depth. The sleeve is sized from the drawer.
+type SleeveDrawer struct {
+	Pull DrawerPull
+	// PullSize is the notch radius or the ribbon width
+	PullSize float64
+	// HiddenLock cuts a sprung tongue in the drawer base that drops through
+	// a window in the sleeve and stops the drawer coming out. Pressing the
+	// marked window pushes the tongue back so the drawer slides free.
+	HiddenLock bool
+}
+
+// DefaultSleeveDrawer has a pull notch and no lock
+func DefaultSleeveDrawer() SleeveDrawer {
+	return SleeveDrawer{Pull: PullNotch, PullSize: 8}
+}
+
+func (SleeveDrawer) Name() string { return "drawer" }
+
+// drawerLayers is the drawer wall layout: the outer wall and the inner wall
+// that folds back down inside it, and the inset of the inner wall's ends so
+// it clears the walls beside it.
+func (b Box) drawerLayers() (outer, inner, inset float64) {
+	return b.Height, b.Height - b.Thickness, 2 * b.Thickness
+}
+
+// lockTongue returns the hidden lock's width and length, and the distance
+// from the drawer front to the tongue's hinge
+func (b Box) lockTongue() (width, length, hinge float64) {
+	width = math.Min(b.Width/4, 20)
+	length = math.Min(b.Depth/6, 15)
+	return width, length, 0.75*b.Depth - length/2
+}
+
+// sleeveSize returns the sleeve panel widths: the inside is the drawer plus
+// sliding clearance, and each panel adds one board thickness for its folds
+func (b Box) sleeveSize() (width, height, length float64) {
+	grow := 2*b.jointClearance() + b.Thickness
+	return b.Width + grow, b.Height + grow, b.Depth
+}
+
+func (s SleeveDrawer) Validate(b Box) error {
+	if len(b.Joints) > 0 || len(b.Windows) > 0 || len(b.Artwork) > 0 {
+		return fmt.Errorf("joints, windows and artwork need the panels of a tube style")
+	}
+	if b.Hanger != nil {
+		if err := b.validateHeader(*b.Hanger, b.Depth); err != nil {
+			return err
+		}
+	}
+	_, inner, inset := b.drawerLayers()
+	if inner <= 0 || b.Width <= 2*inset || b.Depth <= 2*inset {
+		return fmt.Errorf("the drawer is too small for double walls")
+	}
+	switch s.Pull {
+	case PullNotch:
+		if s.PullSize <= 0 || s.PullSize >= inner || 2*s.PullSize >= b.Width-2*inset {
+			return fmt.Errorf("pull notch radius %.1f mm does not fit the front wall", s.PullSize)
+		}
+	case RibbonSlot:
+		if s.PullSize <= 0 || s.PullSize >= b.Width-2*inset {
+			return fmt.Errorf("ribbon slot %.1f mm does not fit the drawer base", s.PullSize)
+		}
+	}
+	if s.HiddenLock {
+		width, length, _ := b.lockTongue()
+		if width < 3*b.windowClearance() || length < 2*b.windowClearance() {
+			return fmt.Errorf("the drawer base is too small for a hidden lock")
+		}
+	}
+	return nil
+}
+
+// Generate returns the drawer and its sleeve as separate dielines
+func (s SleeveDrawer) Generate(b Box) ([]Dieline, error) {
+	drawer, err := s.drawer(b)
+	if err != nil {
+		return nil, err
+	}
+	sleeve, err := s.sleeve(b)
+	if err != nil {
+		return nil, err
+	}
+	return []Dieline{drawer, sleeve}, nil
+}
+
+// drawer lays out the base with its double walls around it. The outer
+// front and back walls carry corner flaps that get trapped between the
+// layers of the side walls.
+func (s SleeveDrawer) drawer(b Box) (Dieline, error) {
+	outer, inner, inset := b.drawerLayers()
+	wall := outer + inner
+	flap := math.Min(0.8*outer, 0.45*b.Depth)
+	taper := 0.15 * outer
+
+	x0, x1 := wall, wall+b.Width
+	y0, y1 := wall, wall+b.Depth
+	width, height := 2*wall+b.Width, 2*wall+b.Depth
+	p := func(x, y float64) pathbuilder.Point { return pathbuilder.Point{X: x, Y: y} }
+
+	// Clockwise from the inner front wall
+	outline := polygon([]pathbuilder.Point{
+		p(x0+inset, 0), p(x1-inset, 0), p(x1-inset, y0-outer), p(x1, y0-outer),
+		p(x1+flap, y0-outer+taper), p(x1+flap, y0-taper), p(x1, y0),
+		p(x1+outer, y0), p(x1+outer, y0+inset), p(width, y0+inset),
+		p(width, y1-inset), p(x1+outer, y1-inset), p(x1+outer, y1), p(x1, y1),
+		p(x1+flap, y1+taper), p(x1+flap, y1+outer-taper), p(x1, y1+outer),
+		p(x1-inset, y1+outer), p(x1-inset, height), p(x0+inset, height), p(x0+inset, y1+outer), p(x0, y1+outer),
+		p(x0-flap, y1+outer-taper), p(x0-flap, y1+taper), p(x0, y1),
+		p(x0-outer, y1), p(x0-outer, y1-inset), p(0, y1-inset),
+		p(0, y0+inset), p(x0-outer, y0+inset), p(x0-outer, y0), p(x0, y0),
+		p(x0-flap, y0-taper), p(x0-flap, y0-outer+taper), p(x0, y0-outer), p(x0+inset, y0-outer),
+	})
+
+	var holes pathbuilder.Path
+	centre := (x0 + x1) / 2
+	frontFold := []pathbuilder.Subpath{line(x0+inset, y0-outer, x1-inset, y0-outer)}
+	switch s.Pull {
+	case PullNotch:
+		// A circle across the top fold becomes a notch in both layers
+		holes.Subpaths = append(holes.Subpaths, pathbuilder.Ellipse(centre, y0-outer, s.PullSize, s.PullSize))
+		frontFold = []pathbuilder.Subpath{
+			line(x0+inset, y0-outer, centre-s.PullSize, y0-outer),
+			line(centre+s.PullSize, y0-outer, x1-inset, y0-outer),
+		}
+	case RibbonSlot:
+		slot := b.slotWidth() * 2
+		holes.Subpaths = append(holes.Subpaths, pathbuilder.Rect(centre-s.PullSize/2, y0+2*inset, s.PullSize, slot))
+	}
+
+	folds := pathbuilder.Path{Subpaths: append(frontFold,
+		pathbuilder.Rect(x0, y0, b.Width, b.Depth),
+		line(x1+outer, y0+inset, x1+outer, y1-inset),
+		line(x0+inset, y1+outer, x1-inset, y1+outer),
+		line(x0-outer, y0+inset, x0-outer, y1-inset),
+		line(x0, y0-outer, x0, y0), line(x1, y0-outer, x1, y0),
+		line(x0, y1, x0, y1+outer), line(x1, y1, x1, y1+outer),
+	)}
+
+	layers := map[string]string{
+		"cut_drawer":      outline.String(),
+		"fold_drawer":     folds.String(),
+		"cut_hole_drawer": holes.String(),
+	}
+	if s.HiddenLock {
+		tongueWidth, tongueLength, hinge := b.lockTongue()
+		radius := tongueWidth / 4
+		left, right := centre-tongueWidth/2, centre+tongueWidth/2
+		top, bottom := y0+hinge, y0+hinge+tongueLength
+		// Cut round three sides, hinged at the front so the free end faces
+		// the back and catches on the sleeve window
+		tongue := pathbuilder.Subpath{Start: p(left, top), Segments: []pathbuilder.Segment{
+			{Op: pathbuilder.LineOp, End: p(left, bottom-radius)},
+			{Op: pathbuilder.QuadOp, Ctrl: p(left, bottom), End: p(left+radius, bottom)},
+			{Op: pathbuilder.LineOp, End: p(right-radius, bottom)},
+			{Op: pathbuilder.QuadOp, Ctrl: p(right, bottom), End: p(right, bottom-radius)},
+			{Op: pathbuilder.LineOp, End: p(right, top)},
+		}}
+		layers["cut_drawer_lock"] = tongue.String()
+		layers["fold_drawer_lock"] = line(left, top, right, top).String()
+	}
+
+	layers, err := b.applyKerf(layers)
+	if err != nil {
+		return Dieline{}, err
+	}
+	return Dieline{Name: "drawer", Layers: layers, Width: width, Height: height}, nil
+}
+
+// sleeve lays out the tube the drawer slides in: top, side, bottom and side
+// panels with a glue tab on the last side. The sleeve's top edge on the
+// sheet is the drawer's front.
+//
+// A hanger stands on the top panel's free edge, left of it on the sheet,
+// so the box hangs with its top facing out and the drawer sliding sideways
+// rather than dropping out of the end.
+func (s SleeveDrawer) sleeve(b Box) (Dieline, error) {
+	top, side, length := b.sleeveSize()
+	tab := math.Min(0.8*side, 15)
+	taper := 0.15 * length
+	var left float64
+	if b.Hanger != nil {
+		left = 2 * b.Hanger.Height
+	}
+	folds := []float64{left + top, left + top + side, left + 2*top + side, left + 2*top + 2*side}
+	width := folds[3] + tab
+
+	// The top panel's free edge, from the back up to the front
+	freeEdge := []pathbuilder.Point{{X: left, Y: length}}
+	topPanel := PanelFrame{
+		Frame:  pathbuilder.Frame{Origin: pathbuilder.Point{X: left, Y: length}, Rotation: 3},
+		Panel:  Lid,
+		Width:  length,
+		Height: top,
+	}
+	if b.Hanger != nil {
+		header := b.hangerPoints(topPanel)
+		freeEdge = header[:len(header)-1]
+	}
+	outline := polygon(append([]pathbuilder.Point{
+		{X: left, Y: 0}, {X: folds[3], Y: 0}, {X: width, Y: taper},
+		{X: width, Y: length - taper}, {X: folds[3], Y: length},
+	}, freeEdge...))
+	var creases pathbuilder.Path
+	for _, x := range folds {
+		creases.Subpaths = append(creases.Subpaths, line(x, 0, x, length))
+	}
+	layers := map[string]string{
+		"cut_sleeve":  outline.String(),
+		"fold_sleeve": creases.String(),
+	}
+	if b.Hanger != nil {
+		layers["fold_hanger"] = b.hangerFold(topPanel)
+		layers["cut_hole_hanger"] = b.hangerHoles(topPanel)
+	}
+
+	if s.HiddenLock {
+		// The window sits under the tongue when the drawer is closed, with
+		// room for the tongue to swing through
+		tongueWidth, tongueLength, hinge := b.lockTongue()
+		clearance := 2 * b.jointClearance()
+		centre := folds[1] + top/2
+		window := pathbuilder.Rect(centre-tongueWidth/2-clearance, hinge-clearance,
+			tongueWidth+2*clearance, tongueLength+2*clearance)
+		layers["cut_hole_sleeve_lock"] = window.String()
+		mark := pathbuilder.Ellipse(centre, hinge+tongueLength/2, tongueWidth, tongueWidth)
+		layers["note_press_mark"] = mark.String()
+	}
+
+	layers, err := b.applyKerf(layers)
+	if err != nil {
+		return Dieline{}, err
+	}
+	return Dieline{Name: "sleeve", Layers: layers, Width: width, Height: length}, nil
+}
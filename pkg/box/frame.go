@@ -0,0 +1,83 @@
+package box
+
+import (
+	"fmt"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Anchor names a point on a panel. The edge centres are the middle of the
+// panel's folds where it has them.
+type Anchor int
+
+const (
+	TopLeft Anchor = iota
+	TopCentre
+	TopRight
+	LeftCentre
+	Centre
+	RightCentre
+	BottomLeft
+	BottomCentre
+	BottomRight
+)
+
+// anchorFractions are the anchors as fractions of the panel's width and height
+var anchorFractions = [...]pathbuilder.Point{
+	TopLeft:      {X: 0, Y: 0},
+	TopCentre:    {X: 0.5, Y: 0},
+	TopRight:     {X: 1, Y: 0},
+	LeftCentre:   {X: 0, Y: 0.5},
+	Centre:       {X: 0.5, Y: 0.5},
+	RightCentre:  {X: 1, Y: 0.5},
+	BottomLeft:   {X: 0, Y: 1},
+	BottomCentre: {X: 0.5, Y: 1},
+	BottomRight:  {X: 1, Y: 1},
+}
+
+func (a Anchor) String() string {
+	names := [...]string{"top left", "top centre", "top right", "left centre", "centre",
+		"right centre", "bottom left", "bottom centre", "bottom right"}
+	if a < 0 || int(a) >= len(names) {
+		return fmt.Sprintf("anchor %d", int(a))
+	}
+	return names[a]
+}
+
+// PanelFrame is a panel's own coordinate system. Local x runs along the
+// panel's width and y down its height from the top left corner, as seen
+// from outside the box; the embedded Frame places that on the sheet.
+type PanelFrame struct {
+	pathbuilder.Frame
+	Panel  Panel
+	Width  float64
+	Height float64
+}
+
+// PanelFrame returns the frame of a panel. Every panel of the tube is laid
+// out upright, so none of them is rotated yet.
+func (b Box) PanelFrame(p Panel) PanelFrame {
+	x, y, w, h := b.PanelRect(p)
+	return PanelFrame{
+		Frame:  pathbuilder.Frame{Origin: pathbuilder.Point{X: x, Y: y}},
+		Panel:  p,
+		Width:  w,
+		Height: h,
+	}
+}
+
+// Anchor returns the anchor in the panel's local coordinates
+func (f PanelFrame) Anchor(a Anchor) pathbuilder.Point {
+	fraction := anchorFractions[a]
+	return pathbuilder.Point{X: fraction.X * f.Width, Y: fraction.Y * f.Height}
+}
+
+// At returns the anchor on the sheet
+func (f PanelFrame) At(a Anchor) pathbuilder.Point {
+	return f.Apply(f.Anchor(a))
+}
+
+// Builder starts a path drawn in the panel's coordinates
+func (f PanelFrame) Builder() *pathbuilder.AdvancedPathBuilder {
+	return pathbuilder.NewAdvancedPathBuilder().InFrame(f.Frame)
+}
@@ -0,0 +1,125 @@
+package box
+
+import (
+	"math"
+	"sort"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// GluePanel selects which end of the panel strip carries the glue tab
+type GluePanel int
+
+const (
+	// GlueOnBack puts the tab on the right edge of the back panel
+	GlueOnBack GluePanel = iota
+	// GlueOnSideA puts the tab on the left edge of side A
+	GlueOnSideA
+)
+
+// GlueTab describes the tab that joins the last panel to the first
+type GlueTab struct {
+	// TaperTop and TaperBottom are the angles in degrees between the tab's
+	// ends and a line square to the fold. Zero gives a plain rectangle.
+	TaperTop    float64
+	TaperBottom float64
+	// MinWidth keeps the tab wide enough to glue on small boxes
+	MinWidth float64
+	Panel    GluePanel
+
+	// ShowGlueArea adds a hatched annotation where adhesive goes
+	ShowGlueArea bool
+	GlueMargin   float64 // distance kept clear of glue inside the tab edges
+	HatchSpacing float64
+}
+
+// DefaultGlueTab tapers both ends by 15 degrees on the back panel
+func DefaultGlueTab() GlueTab {
+	return GlueTab{
+		TaperTop:     15,
+		TaperBottom:  15,
+		MinWidth:     10,
+		Panel:        GlueOnBack,
+		GlueMargin:   2,
+		HatchSpacing: 3,
+	}
+}
+
+// GlueTabWidth is the tab width, never narrower than GlueTab.MinWidth
+func (b Box) GlueTabWidth() float64 {
+	return math.Max(b.SideFlapWidth(), b.GlueTab.MinWidth)
+}
+
+// glueTabInsets returns how far the tapered ends come in along the fold.
+// Steep tapers on a short box are scaled back so the ends never cross.
+func (b Box) glueTabInsets() (top, bottom float64) {
+	width := b.GlueTabWidth()
+	top = width * math.Tan(b.GlueTab.TaperTop*math.Pi/180)
+	bottom = width * math.Tan(b.GlueTab.TaperBottom*math.Pi/180)
+	if limit := b.Height * 0.9; top+bottom > limit {
+		ratio := limit / (top + bottom)
+		top *= ratio
+		bottom *= ratio
+	}
+	return math.Max(top, 0), math.Max(bottom, 0)
+}
+
+// panelsLeft is where side A starts; a tab on side A sits left of it
+func (b Box) panelsLeft() float64 {
+	if b.GlueTab.Panel == GlueOnSideA {
+		return b.GlueTabWidth()
+	}
+	return 0
+}
+
+// GenerateGlueArea hatches the part of the tab that takes adhesive,
+// keeping GlueMargin clear of the cut and fold edges.
+func (b Box) GenerateGlueArea() string {
+	tab, err := pathbuilder.ParsePath(b.GenerateSideTabs())
+	if err != nil || len(tab.Subpaths) == 0 {
+		return ""
+	}
+	// Closing the tab cut along the fold gives the whole tab outline
+	outline := tab.Subpaths[0]
+	outline.Closed = true
+	area := outline.Offset(-b.GlueTab.GlueMargin)
+	path := pathbuilder.Path{Subpaths: []pathbuilder.Subpath{area}}
+	path.Subpaths = append(path.Subpaths, hatch(area.Flatten(0), b.GlueTab.HatchSpacing)...)
+	return path.String()
+}
+
+// hatch fills a polygon with 45 degree lines spaced apart, pairing up edge
+// crossings so concave outlines are hatched correctly too.
+func hatch(polygon []pathbuilder.Point, spacing float64) []pathbuilder.Subpath {
+	if spacing <= 0 || len(polygon) < 3 {
+		return nil
+	}
+	minC, maxC := math.Inf(1), math.Inf(-1)
+	for _, p := range polygon {
+		minC = math.Min(minC, p.X+p.Y)
+		maxC = math.Max(maxC, p.X+p.Y)
+	}
+
+	var lines []pathbuilder.Subpath
+	step := spacing * math.Sqrt2
+	for c := minC + step/2; c < maxC; c += step {
+		var crossings []pathbuilder.Point
+		for i := range polygon {
+			a, b := polygon[i], polygon[(i+1)%len(polygon)]
+			fa, fb := a.X+a.Y-c, b.X+b.Y-c
+			if (fa < 0) == (fb < 0) {
+				continue
+			}
+			t := fa / (fa - fb)
+			crossings = append(crossings, pathbuilder.Point{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t})
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].X < crossings[j].X })
+		for i := 0; i+1 < len(crossings); i += 2 {
+			lines = append(lines, pathbuilder.Subpath{
+				Start:    crossings[i],
+				Segments: []pathbuilder.Segment{{Op: pathbuilder.LineOp, End: crossings[i+1]}},
+			})
+		}
+	}
+	return lines
+}
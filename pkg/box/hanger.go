@@ -0,0 +1,238 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// HangerHole is the shape punched through the header for the peg hook
+type HangerHole int
+
+const (
+	// EuroSlot is a round hole with a wide slot across its top, which
+	// takes both round pegs and flat euro hooks
+	EuroSlot HangerHole = iota
+	RoundHole
+)
+
+func (h HangerHole) String() string {
+	if h == RoundHole {
+		return "round"
+	}
+	return "euro"
+}
+
+// Hanger is a header card above the back of the box for hanging it on a
+// peg hook. It is folded over on itself so the hole goes through two
+// layers of board.
+type Hanger struct {
+	// Height of the finished header above the box, one layer of the two
+	Height float64
+	Hole   HangerHole
+	// HoleDiameter is the round part of either hole
+	HoleDiameter float64
+	// SlotWidth and SlotHeight size the slot across the top of a euro slot
+	SlotWidth  float64
+	SlotHeight float64
+	// HoleX moves the hole sideways from the middle of the header
+	HoleX float64
+	// HoleTop is the distance from the top of the header to the hole
+	HoleTop float64
+}
+
+// DefaultHanger is a 40 mm header with a standard euro slot
+func DefaultHanger() Hanger {
+	return Hanger{
+		Height:       40,
+		Hole:         EuroSlot,
+		HoleDiameter: 10,
+		SlotWidth:    32,
+		SlotHeight:   5,
+		HoleTop:      6,
+	}
+}
+
+// hangerInset narrows the inner layer so it folds down clear of the sides
+func (b Box) hangerInset() float64 {
+	return math.Max(1, 2*b.Thickness)
+}
+
+// holeSize returns the width and height of the hanger hole
+func (h Hanger) holeSize() (width, height float64) {
+	if h.Hole == RoundHole {
+		return h.HoleDiameter, h.HoleDiameter
+	}
+	return math.Max(h.SlotWidth, h.HoleDiameter), h.HoleDiameter
+}
+
+// ValidateHanger checks the header fits above the back panel and the hole
+// fits inside the header
+func (b Box) ValidateHanger(h Hanger) error {
+	if 2*h.Height > float64(b.Top()) {
+		return fmt.Errorf("a %.1f mm hanger needs %.1f mm above the back panel, only %d mm is free", h.Height, 2*h.Height, b.Top())
+	}
+	return b.validateHeader(h, b.Width)
+}
+
+// validateHeader checks the hole fits inside a header of the given width.
+// Pieces cut on their own have no panel above to run into, so this is all
+// they check.
+func (b Box) validateHeader(h Hanger, width float64) error {
+	if h.Height <= 0 {
+		return fmt.Errorf("hanger needs a height")
+	}
+	if h.HoleDiameter <= 0 || (h.Hole == EuroSlot && (h.SlotHeight <= 0 || h.SlotHeight >= h.HoleDiameter)) {
+		return fmt.Errorf("%s hanger hole needs a diameter larger than its slot height", h.Hole)
+	}
+	holeWidth, holeHeight := h.holeSize()
+	clearance := b.windowClearance()
+	if h.HoleTop < clearance || h.HoleTop+holeHeight > h.Height-clearance {
+		return fmt.Errorf("%s hanger hole must stay %.1f mm inside the header", h.Hole, clearance)
+	}
+	if math.Abs(h.HoleX)+holeWidth/2 > width/2-b.hangerInset()-clearance {
+		return fmt.Errorf("%s hanger hole runs off the side of the header", h.Hole)
+	}
+	return nil
+}
+
+// hangerCorners lists the corners of the header outline in the frame of the
+// panel it stands on, from the panel's top right round to its top left. The
+// inner layer above the fold steps in at both sides, cut along the fold line.
+func (b Box) hangerCorners(f PanelFrame) [][2]int {
+	width := int(f.Width)
+	height := int(b.Hanger.Height)
+	inset := b.hangerFoldInset()
+	return [][2]int{
+		{width, 0}, {width, -height}, {width - inset, -height}, {width - inset, -2 * height},
+		{inset, -2 * height}, {inset, -height}, {0, -height}, {0, 0},
+	}
+}
+
+// hangerPoints places the header outline on the sheet for a piece drawn as
+// a polygon. It runs from the panel's top left to its top right, the way a
+// clockwise outline passes a panel whose top faces out of the piece.
+func (b Box) hangerPoints(f PanelFrame) []pathbuilder.Point {
+	corners := b.hangerCorners(f)
+	points := make([]pathbuilder.Point, len(corners))
+	for i, c := range corners {
+		points[len(corners)-1-i] = f.Apply(pathbuilder.Point{X: float64(c[0]), Y: float64(c[1])})
+	}
+	return points
+}
+
+// GenerateHangerOutline draws the header round from the back panel's top
+// right corner to its top left, in place of the plain top edge
+func (b Box) GenerateHangerOutline() string {
+	corners := b.hangerCorners(b.PanelFrame(Back))
+	path := b.PanelFrame(Back).Builder().MoveTo(corners[0][0], corners[0][1])
+	for _, c := range corners[1:] {
+		path.LineTo(c[0], c[1]).Square()
+	}
+	return path.Build().String()
+}
+
+// hangerFoldInset is the whole mm the inner layer steps in at each side
+func (b Box) hangerFoldInset() int {
+	return int(math.Ceil(b.hangerInset()))
+}
+
+// GenerateHangerFold creates the crease along the top of the header. It
+// stops where the outline steps in, since only the width of the inner
+// layer folds.
+func (b Box) GenerateHangerFold() string {
+	return b.hangerFold(b.PanelFrame(Back))
+}
+
+func (b Box) hangerFold(f PanelFrame) string {
+	width := int(f.Width)
+	inset := b.hangerFoldInset()
+	return f.Builder().
+		MoveTo(inset, -int(b.Hanger.Height)).
+		HorizontalLine(width - 2*inset).Square().
+		Build().String()
+}
+
+// GenerateHangerHoles cuts the hole in both layers. The inner layer is the
+// outer one mirrored about the fold, so the holes line up once folded.
+func (b Box) GenerateHangerHoles() string {
+	return b.hangerHoles(b.PanelFrame(Back))
+}
+
+func (b Box) hangerHoles(f PanelFrame) string {
+	h := *b.Hanger
+	centreX := f.Width/2 + h.HoleX
+	fold := -h.Height
+	holes := pathbuilder.Path{Subpaths: []pathbuilder.Subpath{
+		h.holeOutline(centreX, fold, 1),
+		h.holeOutline(centreX, fold, -1),
+	}}
+	return f.Path(holes).String()
+}
+
+// holeOutline draws the hole hanging from the fold at y. Direction 1 is
+// down into the outer layer, -1 up into the inner layer.
+func (h Hanger) holeOutline(x, fold, direction float64) pathbuilder.Subpath {
+	radius := h.HoleDiameter / 2
+	centre := pathbuilder.Point{X: x, Y: fold + direction*(h.HoleTop+radius)}
+	if h.Hole == RoundHole {
+		return pathbuilder.Ellipse(centre.X, centre.Y, radius, radius)
+	}
+
+	// The slot runs across the top of the round hole, both flush at the top
+	capRadius := h.SlotHeight / 2
+	halfWidth := math.Max(h.SlotWidth/2, radius)
+	top := fold + direction*h.HoleTop
+	meet := math.Sqrt(radius*radius - (radius-h.SlotHeight)*(radius-h.SlotHeight))
+
+	// Point on the slot in local terms: across from the centre, down from the top
+	at := func(across, down float64) pathbuilder.Point {
+		return pathbuilder.Point{X: x + across, Y: top + direction*down}
+	}
+	line := func(p pathbuilder.Point) pathbuilder.Segment {
+		return pathbuilder.Segment{Op: pathbuilder.LineOp, End: p}
+	}
+	quad := func(ctrl, end pathbuilder.Point) pathbuilder.Segment {
+		return pathbuilder.Segment{Op: pathbuilder.QuadOp, Ctrl: ctrl, End: end}
+	}
+	sp := pathbuilder.Subpath{Start: at(-halfWidth+capRadius, 0), Closed: true}
+	sp.Segments = append(sp.Segments,
+		line(at(halfWidth-capRadius, 0)),
+		quad(at(halfWidth, 0), at(halfWidth, capRadius)),
+		quad(at(halfWidth, h.SlotHeight), at(halfWidth-capRadius, h.SlotHeight)),
+		line(at(meet, h.SlotHeight)),
+	)
+	// Round the bottom of the hole from one side of the slot to the other
+	above := math.Atan2(radius-h.SlotHeight, meet)
+	sp.Segments = append(sp.Segments, arc(centre, radius, -above, math.Pi+above, direction)...)
+	sp.Segments = append(sp.Segments,
+		line(at(-halfWidth+capRadius, h.SlotHeight)),
+		quad(at(-halfWidth, h.SlotHeight), at(-halfWidth, capRadius)),
+		quad(at(-halfWidth, 0), at(-halfWidth+capRadius, 0)),
+	)
+	return sp
+}
+
+// arc approximates a circular arc with quadratic segments of at most 45
+// degrees. Angles are measured from the x axis towards the direction side
+// of the centre, so direction -1 mirrors the arc about its centre line.
+func arc(centre pathbuilder.Point, radius, from, to, direction float64) []pathbuilder.Segment {
+	count := int(math.Ceil(math.Abs(to-from) / (math.Pi / 4)))
+	step := (to - from) / float64(count)
+	at := func(angle, scale float64) pathbuilder.Point {
+		return pathbuilder.Point{
+			X: centre.X + radius*scale*math.Cos(angle),
+			Y: centre.Y + direction*radius*scale*math.Sin(angle),
+		}
+	}
+	var segments []pathbuilder.Segment
+	for i := 1; i <= count; i++ {
+		segments = append(segments, pathbuilder.Segment{
+			Op:   pathbuilder.QuadOp,
+			Ctrl: at(from+(float64(i)-0.5)*step, 1/math.Cos(step/2)),
+			End:  at(from+float64(i)*step, 1),
+		})
+	}
+	return segments
+}
@@ -0,0 +1,135 @@
+package box
+
+import (
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+func TestHangerOnPieceStyles(t *testing.T) {
+	tests := []struct {
+		style Style
+		piece string
+		cut   string
+		// grow is which way the piece gets bigger to make room for the header
+		growX, growY bool
+	}{
+		{DefaultTelescoping(), "base", "cut_base", false, true},
+		{DefaultSleeveDrawer(), "sleeve", "cut_sleeve", true, false},
+	}
+	for _, tt := range tests {
+		b := *NewBox(100, 70, 120, 0)
+		plain, err := tt.style.Generate(b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.style.Name(), err)
+		}
+		h := DefaultHanger()
+		b.Hanger = &h
+		if err := tt.style.Validate(b); err != nil {
+			t.Fatalf("%s: %v", tt.style.Name(), err)
+		}
+		pieces, err := tt.style.Generate(b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.style.Name(), err)
+		}
+		for i, d := range pieces {
+			if d.Name != tt.piece {
+				continue
+			}
+			if (d.Width > plain[i].Width) != tt.growX || (d.Height > plain[i].Height) != tt.growY {
+				t.Errorf("%s: %.1f by %.1f piece grew the wrong way from %.1f by %.1f",
+					tt.style.Name(), d.Width, d.Height, plain[i].Width, plain[i].Height)
+			}
+			outline, err := pathbuilder.ParsePath(d.Layers[tt.cut])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(outline.Subpaths) != 1 || !outline.Subpaths[0].Closed {
+				t.Fatalf("%s: the header should be part of the one closed outline", tt.style.Name())
+			}
+			minX, minY, maxX, maxY := outline.Bounds()
+			if minX < -1 || minY < -1 || maxX > d.Width+1 || maxY > d.Height+1 {
+				t.Errorf("%s: outline runs off the %.1f by %.1f piece", tt.style.Name(), d.Width, d.Height)
+			}
+			// Both holes and the fold sit on the header inside the outline
+			for _, layer := range []string{"cut_hole_hanger", "fold_hanger"} {
+				path, err := pathbuilder.ParsePath(d.Layers[layer])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if layer == "cut_hole_hanger" && len(path.Subpaths) != 2 {
+					t.Errorf("%s: %d hanger holes, want 2", tt.style.Name(), len(path.Subpaths))
+				}
+				for _, ring := range path.Flatten(0.1) {
+					// The fold ends on the outline where the inner layer steps in
+					if layer == "fold_hanger" {
+						ring = []pathbuilder.Point{{X: (ring[0].X + ring[1].X) / 2, Y: (ring[0].Y + ring[1].Y) / 2}}
+					}
+					for _, pt := range ring {
+						if !outline.Contains(pt) {
+							t.Errorf("%s: %s point %v is outside the outline", tt.style.Name(), layer, pt)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTelescopingLidSlotsOverHanger(t *testing.T) {
+	b := *NewBox(100, 70, 120, 0)
+	h := DefaultHanger()
+	b.Hanger = &h
+	style := DefaultTelescoping()
+	pieces, err := style.Generate(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lid := pieces[1]
+	outline, err := pathbuilder.ParsePath(lid.Layers["cut_lid"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot, err := pathbuilder.ParsePath(lid.Layers["cut_hole_lid"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The slot takes the full width of the header and both its layers, and
+	// stays on the lid's top inside the back fold
+	minX, minY, maxX, maxY := slot.Bounds()
+	if maxX-minX < b.Width || maxY-minY < 2*b.Thickness {
+		t.Errorf("%.1f by %.1f slot is too small for the header", maxX-minX, maxY-minY)
+	}
+	width, depth, wall := style.lidSize(b)
+	if minX < wall || maxX > wall+width || maxY > wall+depth || minY < wall {
+		t.Errorf("slot %.1f,%.1f to %.1f,%.1f is not on the lid's top", minX, minY, maxX, maxY)
+	}
+	for _, ring := range slot.Flatten(0.1) {
+		for _, pt := range ring {
+			if !outline.Contains(pt) {
+				t.Errorf("slot point %v is outside the lid", pt)
+			}
+		}
+	}
+}
+
+func TestHangerNeedsRoom(t *testing.T) {
+	b := *NewBox(100, 40, 120, 0)
+	h := DefaultHanger()
+	h.HoleX = 40
+	b.Hanger = &h
+	if err := DefaultTelescoping().Validate(b); err == nil {
+		t.Error("telescoping: a hole off the side of the header should not validate")
+	}
+	b.Depth = 30
+	h.HoleX = 0
+	b.Hanger = &h
+	if err := DefaultSleeveDrawer().Validate(b); err == nil {
+		t.Error("drawer: a header as long as a 30 mm sleeve has no room for a euro slot")
+	}
+	b = *NewBox(100, 40, 120, 0)
+	b.Hanger = &h
+	if err := DefaultHingedLid().Validate(b); err == nil {
+		t.Error("hinged: the lid hinges where the header would go")
+	}
+}
@@ -0,0 +1,209 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// HingedLid extends the back panel into a lid with flaps that fold down
+// inside the sides and a tuck that drops inside the front panel. It can't
+// take a hanger, since the lid takes the back panel's top edge.
+type HingedLid struct {
+	// ThumbNotchRadius cuts a half circle into the front panel's top edge so
+	// the lid can be lifted. Zero leaves the edge straight.
+	ThumbNotchRadius float64
+
+	// Magnets are positions across the front as fractions of Width. Each one
+	// gets a recess in the front panel and a matching one in the tuck.
+	Magnets        []float64
+	MagnetDiameter float64
+	// MagnetInset is the distance from the front panel's top edge, and from
+	// the tuck fold, to the centre of each magnet.
+	MagnetInset float64
+}
+
+// DefaultHingedLid has a thumb notch and no magnets
+func DefaultHingedLid() HingedLid {
+	return HingedLid{
+		ThumbNotchRadius: 12,
+		MagnetDiameter:   8,
+		MagnetInset:      10,
+	}
+}
+
+func (HingedLid) Name() string { return "hinged" }
+
+func (h HingedLid) Validate(b Box) error {
+	b.lidOnBack = true
+	if b.Hanger != nil {
+		return fmt.Errorf("the lid is hinged on the back panel, which leaves no room for a hanger")
+	}
+	for _, j := range b.Joints {
+		if mate, _ := Mate(j.Edge); j.Edge.Panel == Lid || mate.Panel == Lid {
+			return fmt.Errorf("the hinged lid's edges carry its flaps and tuck, which leaves no room for a joint on the %s", j.Edge)
+		}
+	}
+	if err := b.validateTube(); err != nil {
+		return err
+	}
+	if h.ThumbNotchRadius < 0 || 2*h.ThumbNotchRadius >= b.Width || h.ThumbNotchRadius >= b.Height/2 {
+		return fmt.Errorf("thumb notch radius %.1f mm does not fit the front panel", h.ThumbNotchRadius)
+	}
+	radius := h.MagnetDiameter / 2
+	for _, f := range h.Magnets {
+		x := f * b.Width
+		if x-radius <= 0 || x+radius >= b.Width {
+			return fmt.Errorf("magnet at %.2f of the width runs off the front panel", f)
+		}
+		if math.Abs(x-b.Width/2) < h.ThumbNotchRadius+radius && h.MagnetInset-radius < h.ThumbNotchRadius {
+			return fmt.Errorf("magnet at %.2f of the width cuts into the thumb notch", f)
+		}
+	}
+	if len(h.Magnets) > 0 && h.MagnetInset+radius >= b.TopFlapHeight() {
+		return fmt.Errorf("magnets %.1f mm in do not fit the %.1f mm tuck", h.MagnetInset, b.TopFlapHeight())
+	}
+	return nil
+}
+
+func (h HingedLid) Generate(b Box) ([]Dieline, error) {
+	layers, err := h.layers(b)
+	if err != nil {
+		return nil, err
+	}
+	return []Dieline{b.BoxDieline(h.Name(), layers)}, nil
+}
+
+// layers creates the tube with the lid in place of the tuck closure
+func (h HingedLid) layers(b Box) (map[string]string, error) {
+	b.lidOnBack = true
+	paths := b.generateTube()
+	paths["fold_lines"] = h.foldLines(b)
+	paths["cut_hinged_lid"] = h.lidCuts(b)
+	paths["fold_hinged_lid"] = h.lidFolds(b)
+	if h.ThumbNotchRadius > 0 {
+		paths["cut_hole_thumb_notch"] = h.thumbNotch(b)
+	}
+	if len(h.Magnets) > 0 {
+		paths["cut_hole_magnets"] = h.magnetRecesses(b)
+	}
+	return b.applyKerf(paths)
+}
+
+// lidFlapWidth keeps the lid flaps within the strip the glue tab reserves
+func (b Box) lidFlapWidth() int {
+	return int(math.Min(0.8*b.GlueTabWidth(), 0.25*b.Depth))
+}
+
+// lidCuts outlines the lid with its side flaps and front tuck, and the free
+// top edges of the side and front panels either side of the thumb notch.
+func (h HingedLid) lidCuts(b Box) string {
+	flap := b.lidFlapWidth()
+	relief := flap / 2
+	tuck := int(b.TopFlapHeight())
+	radius := int(math.Min(0.8*b.TopFlapHeight(), b.Width/4))
+
+	lid := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelLeft(), b.Top()).
+		RelativeLine(-flap, -relief).Square().
+		VerticalLine(-(b.D()-2*relief)).Square().
+		RelativeLine(flap, -relief).Square().
+		VerticalLine(-tuck).Rounded(radius).
+		HorizontalLine(b.W()).Rounded(radius).
+		VerticalLine(tuck).Square().
+		RelativeLine(flap, relief).Square().
+		VerticalLine(b.D()-2*relief).Square().
+		RelativeLine(-flap, relief).Square().
+		Build().String()
+
+	// Top edges are drawn right to left so kerf compensation moves them up
+	notchLeft := b.FrontPanelLeft() + b.W()/2 - int(h.ThumbNotchRadius)
+	notchRight := b.FrontPanelLeft() + b.W()/2 + int(h.ThumbNotchRadius)
+	right := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelLeft(), b.Top()).
+		LineTo(notchRight, b.Top()).Square().
+		Build().String()
+	left := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(notchLeft, b.Top()).
+		LineTo(b.SideALeft(), b.Top()).Square().
+		Build().String()
+
+	return lid + right + left
+}
+
+// foldLines keeps the bottom, seam and panel folds of the tube, but only the back
+// panel's top edge folds; it is the hinge. The other top edges are cut free.
+func (h HingedLid) foldLines(b Box) string {
+	seamX := b.BackRight()
+	if b.GlueTab.Panel == GlueOnSideA {
+		seamX = b.SideALeft()
+	}
+	bottom := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.SideALeft(), b.Bottom()).
+		LineTo(b.BackRight(), b.Bottom()).Square().
+		Build().String()
+	// A joint on the seam is cut with its tabs instead
+	var seam string
+	if _, ok := b.seamJoint(); !ok {
+		seam = pathbuilder.NewAdvancedPathBuilder().
+			MoveTo(seamX, b.Bottom()).
+			VerticalLine(-b.H()).Square().
+			Build().String()
+	}
+	hinge := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelLeft(), b.Top()).
+		HorizontalLine(b.W()).Square().
+		Build().String()
+	return bottom + seam + hinge + b.panelFolds()
+}
+
+// lidFolds creates the creases for the side flaps and the tuck. The hinge
+// itself is the top of the fold lines.
+func (h HingedLid) lidFolds(b Box) string {
+	lidTop := b.Top() - b.D()
+	leftFlap := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelLeft(), lidTop).
+		VerticalLine(b.D()).Square().
+		Build().String()
+	rightFlap := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelRight(), lidTop).
+		VerticalLine(b.D()).Square().
+		Build().String()
+	tuck := pathbuilder.NewAdvancedPathBuilder().
+		MoveTo(b.BackPanelLeft(), lidTop).
+		HorizontalLine(b.W()).Square().
+		Build().String()
+	return leftFlap + rightFlap + tuck
+}
+
+// thumbNotch cuts a half circle down into the middle of the front panel
+func (h HingedLid) thumbNotch(b Box) string {
+	front := b.PanelFrame(Front)
+	centre := front.Anchor(TopCentre)
+	radius := int(h.ThumbNotchRadius)
+	return front.Builder().
+		MoveTo(int(centre.X)-radius, 0).
+		ArcTo(radius, radius, 0, false, false, int(centre.X)+radius, 0).Square().
+		Build().String()
+}
+
+// magnetRecesses places each magnet in the front panel and its partner in
+// the tuck. The lid is hinged on the back, so the tuck runs the opposite way
+// to the front panel once closed.
+//
+// Unlike the rest of the style these don't go through AdvancedPathBuilder.
+// It works in whole mm, and a recess has to match the magnet: a 6.35 mm
+// magnet would get a 6 mm hole it can't be pressed into.
+func (h HingedLid) magnetRecesses(b Box) string {
+	front := b.PanelFrame(Front)
+	back := b.PanelFrame(Back)
+	radius := h.MagnetDiameter / 2
+	var inFront, inTuck pathbuilder.Path
+	for _, f := range h.Magnets {
+		inFront.Subpaths = append(inFront.Subpaths, pathbuilder.Ellipse(f*b.Width, h.MagnetInset, radius, radius))
+		// The tuck lies above the back panel, past the lid
+		inTuck.Subpaths = append(inTuck.Subpaths, pathbuilder.Ellipse((1-f)*b.Width, -b.Depth-h.MagnetInset, radius, radius))
+	}
+	return front.Path(inFront).String() + back.Path(inTuck).String()
+}
@@ -0,0 +1,262 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Grid divides the inside of the box into compartments with slotted divider
+// strips. Columns split the width and rows split the depth.
+type Grid struct {
+	Rows    int
+	Columns int
+	// RowSizes and ColumnSizes give each compartment's size in mm and take
+	// the place of Rows and Columns. Space left over goes to the last one.
+	RowSizes    []float64
+	ColumnSizes []float64
+	// Tray adds a folded tray that lines the box and holds the dividers
+	Tray bool
+}
+
+// insertGap separates the pieces of the insert dieline
+const insertGap = 10
+
+// Inside returns the space inside the folded box. The crash-lock bottom
+// puts two layers of board on the floor.
+func (b Box) Inside() (width, depth, height float64) {
+	return b.Width - 2*b.Thickness, b.Depth - 2*b.Thickness, b.Height - 2*b.Thickness
+}
+
+// trayOutside is the folded tray's size, a clearance smaller than the inside
+func (b Box) trayOutside() (width, depth, height float64) {
+	width, depth, height = b.Inside()
+	clearance := 2 * b.jointClearance()
+	return width - clearance, depth - clearance, height
+}
+
+// dividerSpace is the room the dividers stand in: inside the tray when
+// there is one, otherwise inside the box
+func (b Box) dividerSpace(g Grid) (width, depth, height float64) {
+	if !g.Tray {
+		width, depth, height = b.Inside()
+	} else {
+		width, depth, height = b.trayOutside()
+		width, depth, height = width-2*b.Thickness, depth-2*b.Thickness, height-b.Thickness
+	}
+	clearance := 2 * b.jointClearance()
+	return width - clearance, depth - clearance, height
+}
+
+// dividerCentres returns where each divider runs along an axis of the given
+// length, measured to the middle of the board.
+func (b Box) dividerCentres(count int, sizes []float64, length float64) []float64 {
+	if len(sizes) == 0 {
+		if count < 2 {
+			return nil
+		}
+		cell := (length - float64(count-1)*b.Thickness) / float64(count)
+		sizes = make([]float64, count)
+		for i := range sizes {
+			sizes[i] = cell
+		}
+	}
+	var centres []float64
+	position := 0.0
+	for _, size := range sizes[:len(sizes)-1] {
+		position += size
+		centres = append(centres, position+b.Thickness/2)
+		position += b.Thickness
+	}
+	return centres
+}
+
+// ValidateGrid checks the compartments fit inside the box
+func (b Box) ValidateGrid(g Grid) error {
+	width, depth, height := b.dividerSpace(g)
+	if width <= 0 || depth <= 0 || height <= 0 {
+		return fmt.Errorf("the box is too small for an insert")
+	}
+	axes := []struct {
+		name   string
+		count  int
+		sizes  []float64
+		length float64
+	}{
+		{"column", g.Columns, g.ColumnSizes, width},
+		{"row", g.Rows, g.RowSizes, depth},
+	}
+	for _, axis := range axes {
+		if len(axis.sizes) == 0 {
+			if axis.count < 1 {
+				return fmt.Errorf("grid needs at least one %s", axis.name)
+			}
+			cell := (axis.length - float64(axis.count-1)*b.Thickness) / float64(axis.count)
+			if cell <= b.slotWidth() {
+				return fmt.Errorf("%d %ss do not fit in %.1f mm", axis.count, axis.name, axis.length)
+			}
+			continue
+		}
+		total := float64(len(axis.sizes)-1) * b.Thickness
+		for _, size := range axis.sizes {
+			if size <= 0 {
+				return fmt.Errorf("%s sizes must be positive", axis.name)
+			}
+			total += size
+		}
+		if total > axis.length {
+			return fmt.Errorf("%ss need %.1f mm but only %.1f mm is inside", axis.name, total, axis.length)
+		}
+	}
+	if !g.Tray && len(b.dividerCentres(g.Columns, g.ColumnSizes, width)) == 0 &&
+		len(b.dividerCentres(g.Rows, g.RowSizes, depth)) == 0 {
+		return fmt.Errorf("a single compartment needs neither dividers nor tray")
+	}
+	return nil
+}
+
+// GenerateInsert creates the divider strips and, if asked for, the tray as
+// a separate dieline. Strips across the width are slotted from the top and
+// strips front to back from the bottom, half their height each, so every
+// crossing slides together flush.
+func (b Box) GenerateInsert(g Grid) (Dieline, error) {
+	width, depth, height := b.dividerSpace(g)
+	columns := b.dividerCentres(g.Columns, g.ColumnSizes, width)
+	rows := b.dividerCentres(g.Rows, g.RowSizes, depth)
+
+	insert := Dieline{Name: "insert", Layers: map[string]string{}}
+	y := 0.0
+	if g.Tray {
+		cuts, folds, trayWidth, trayHeight := b.tray()
+		insert.Layers["cut_insert_tray"] = cuts
+		insert.Layers["fold_insert_tray"] = folds
+		insert.Width = trayWidth
+		y = trayHeight + insertGap
+	}
+
+	var dividers pathbuilder.Path
+	for range rows {
+		dividers.Subpaths = append(dividers.Subpaths, b.dividerStrip(0, y, width, height, columns, true))
+		insert.Width = math.Max(insert.Width, width)
+		y += height + insertGap
+	}
+	for range columns {
+		dividers.Subpaths = append(dividers.Subpaths, b.dividerStrip(0, y, depth, height, rows, false))
+		insert.Width = math.Max(insert.Width, depth)
+		y += height + insertGap
+	}
+	if len(dividers.Subpaths) > 0 {
+		insert.Layers["cut_insert_dividers"] = dividers.String()
+	}
+	insert.Height = math.Max(y-insertGap, 0)
+	var err error
+	if insert.Layers, err = b.applyKerf(insert.Layers); err != nil {
+		return Dieline{}, err
+	}
+	return insert, nil
+}
+
+// dividerStrip outlines one strip clockwise from its top left corner, with
+// a half height slot centred on each crossing.
+func (b Box) dividerStrip(x, y, length, height float64, crossings []float64, fromTop bool) pathbuilder.Subpath {
+	half := b.slotWidth() / 2
+	points := []pathbuilder.Point{{X: x, Y: y}}
+	if fromTop {
+		for _, c := range crossings {
+			points = append(points,
+				pathbuilder.Point{X: x + c - half, Y: y},
+				pathbuilder.Point{X: x + c - half, Y: y + height/2},
+				pathbuilder.Point{X: x + c + half, Y: y + height/2},
+				pathbuilder.Point{X: x + c + half, Y: y})
+		}
+	}
+	points = append(points,
+		pathbuilder.Point{X: x + length, Y: y},
+		pathbuilder.Point{X: x + length, Y: y + height})
+	if !fromTop {
+		for i := len(crossings) - 1; i >= 0; i-- {
+			c := crossings[i]
+			points = append(points,
+				pathbuilder.Point{X: x + c + half, Y: y + height},
+				pathbuilder.Point{X: x + c + half, Y: y + height/2},
+				pathbuilder.Point{X: x + c - half, Y: y + height/2},
+				pathbuilder.Point{X: x + c - half, Y: y + height})
+		}
+	}
+	points = append(points, pathbuilder.Point{X: x, Y: y + height})
+	return polygon(points)
+}
+
+// tray draws the insert tray, see trayLayout
+func (b Box) tray() (cuts, folds string, width, height float64) {
+	return trayLayout(b.trayOutside())
+}
+
+// trayLayout draws an open tray: the base with a wall on each side and a
+// glue flap at both ends of the front and back walls that folds inside the
+// side walls. It returns the cut and fold paths and the size of the layout.
+// Any backEdge points replace the back wall's free edge, running from its
+// right end to its left.
+func trayLayout(base, baseDepth, wall float64, backEdge ...pathbuilder.Point) (cuts, folds string, width, height float64) {
+	flap := math.Min(0.8*wall, 0.45*baseDepth)
+	taper := 0.15 * wall
+
+	x0, x1 := wall, wall+base
+	y0, y1 := wall, wall+baseDepth
+	width, height = 2*wall+base, 2*wall+baseDepth
+
+	if len(backEdge) == 0 {
+		backEdge = []pathbuilder.Point{{X: x1, Y: height}, {X: x0, Y: height}}
+	}
+
+	// Clockwise from the front wall, one corner flap at a time
+	points := []pathbuilder.Point{
+		{X: x0, Y: 0}, {X: x1, Y: 0},
+		{X: x1 + flap, Y: taper}, {X: x1 + flap, Y: y0 - taper}, {X: x1, Y: y0},
+		{X: width, Y: y0}, {X: width, Y: y1}, {X: x1, Y: y1},
+		{X: x1 + flap, Y: y1 + taper}, {X: x1 + flap, Y: height - taper},
+	}
+	points = append(points, backEdge...)
+	points = append(points, []pathbuilder.Point{
+		{X: x0 - flap, Y: height - taper}, {X: x0 - flap, Y: y1 + taper}, {X: x0, Y: y1},
+		{X: 0, Y: y1}, {X: 0, Y: y0}, {X: x0, Y: y0},
+		{X: x0 - flap, Y: y0 - taper}, {X: x0 - flap, Y: taper},
+	}...)
+	outline := polygon(points)
+
+	creases := pathbuilder.Path{Subpaths: []pathbuilder.Subpath{
+		pathbuilder.Rect(x0, y0, base, baseDepth),
+		line(x0, 0, x0, y0), line(x1, 0, x1, y0),
+		line(x0, y1, x0, height), line(x1, y1, x1, height),
+	}}
+	return outline.String(), creases.String(), width, height
+}
+
+// trayBackWall is the frame of a tray's back wall, turned so its top is the
+// free edge at the bottom of the layout
+func trayBackWall(base, baseDepth, wall float64) PanelFrame {
+	return PanelFrame{
+		Frame:  pathbuilder.Frame{Origin: pathbuilder.Point{X: wall + base, Y: 2*wall + baseDepth}, Rotation: 2},
+		Panel:  Back,
+		Width:  base,
+		Height: wall,
+	}
+}
+
+// polygon joins the points with straight lines into a closed subpath
+func polygon(points []pathbuilder.Point) pathbuilder.Subpath {
+	sp := pathbuilder.Subpath{Start: points[0], Closed: true}
+	for _, p := range points[1:] {
+		sp.Segments = append(sp.Segments, pathbuilder.Segment{Op: pathbuilder.LineOp, End: p})
+	}
+	return sp
+}
+
+// line is a single straight stroke
+func line(x0, y0, x1, y1 float64) pathbuilder.Subpath {
+	return pathbuilder.Subpath{
+		Start:    pathbuilder.Point{X: x0, Y: y0},
+		Segments: []pathbuilder.Segment{{Op: pathbuilder.LineOp, End: pathbuilder.Point{X: x1, Y: y1}}},
+	}
+}
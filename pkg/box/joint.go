@@ -0,0 +1,168 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Joint is a row of tabs along one panel edge that lock into slots on the
+// edge it meets when folded, so the box holds together without glue.
+type Joint struct {
+	Edge PanelEdge
+	Tabs int
+	// TabLength is how far the tabs stand out from the edge. Zero picks a
+	// length from the material thickness.
+	TabLength float64
+}
+
+// JointPaths holds the generated geometry of a joint
+type JointPaths struct {
+	Tabs  string // cut along the tabbed edge, round every tab
+	Folds string // creases across the tab roots
+	Slots string // closed slot outlines in the mating panel
+}
+
+// jointClearance is the play added round each tab so it slides into its slot
+func (b Box) jointClearance() float64 {
+	return math.Max(0.2, b.Thickness/4)
+}
+
+func (b Box) tabLength(j Joint) float64 {
+	if j.TabLength > 0 {
+		return j.TabLength
+	}
+	return math.Max(5, 10*b.Thickness)
+}
+
+// slotWidth is the slot size across the edge: the material plus play
+func (b Box) slotWidth() float64 {
+	return b.Thickness + 2*b.jointClearance()
+}
+
+// ValidateJoint checks the edge has a mate and that the tabs and slots fit
+func (b Box) ValidateJoint(j Joint) error {
+	mate, ok := Mate(j.Edge)
+	if !ok {
+		return fmt.Errorf("%s does not meet another edge when folded", j.Edge)
+	}
+	if j.Tabs < 1 {
+		return fmt.Errorf("joint on %s needs at least one tab", j.Edge)
+	}
+
+	x0, y0, x1, y1, _, _ := b.edgeLine(j.Edge)
+	tabWidth := math.Hypot(x1-x0, y1-y0) / float64(2*j.Tabs)
+	if tabWidth <= 2*b.jointClearance() {
+		return fmt.Errorf("%d tabs are too narrow for %s", j.Tabs, j.Edge)
+	}
+
+	// The tab folds round the mating panel's thickness before entering the slot
+	if reach := b.Thickness + b.slotWidth(); b.tabLength(j) <= reach {
+		return fmt.Errorf("tabs on %s must be longer than %.1f mm to pass through the slots", j.Edge, reach)
+	}
+
+	_, _, w, h := b.PanelRect(mate.Panel)
+	depth := h
+	if mate.Edge == LeftEdge || mate.Edge == RightEdge {
+		depth = w
+	}
+	if b.Thickness+b.slotWidth() >= depth {
+		return fmt.Errorf("slots do not fit inside %s", mate.Panel)
+	}
+	return nil
+}
+
+// GenerateJoint creates the tabs on the joint's edge and the matching slots
+// on its mate. Tabs are spread evenly, each as wide as the gap beside it.
+// Slots sit one material thickness in from the mating edge, where the tab
+// arrives after folding round the corner.
+func (b Box) GenerateJoint(j Joint) JointPaths {
+	mate, _ := Mate(j.Edge)
+	x0, y0, x1, y1, inX, inY := b.edgeLine(j.Edge)
+	length := math.Hypot(x1-x0, y1-y0)
+	dirX, dirY := (x1-x0)/length, (y1-y0)/length
+
+	tabLength := b.tabLength(j)
+	tabWidth := length / float64(2*j.Tabs)
+	outX, outY := int(math.Round(-inX*tabLength)), int(math.Round(-inY*tabLength))
+
+	at := func(distance float64) (int, int) {
+		return int(math.Round(x0 + dirX*distance)), int(math.Round(y0 + dirY*distance))
+	}
+
+	tabs := pathbuilder.NewAdvancedPathBuilder().MoveTo(at(0))
+	var folds string
+	for i := 0; i < j.Tabs; i++ {
+		startX, startY := at(tabWidth * (float64(2*i) + 0.5))
+		endX, endY := at(tabWidth * (float64(2*i) + 1.5))
+		tabs = tabs.
+			LineTo(startX, startY).Square().
+			RelativeLine(outX, outY).Rounded(int(tabLength/3)).
+			LineTo(endX+outX, endY+outY).Rounded(int(tabLength/3)).
+			LineTo(endX, endY).Square()
+		folds += pathbuilder.NewAdvancedPathBuilder().
+			MoveTo(startX, startY).
+			LineTo(endX, endY).Square().
+			Build().String()
+	}
+	tabs = tabs.LineTo(at(length)).Square()
+
+	// Slots on the mating edge, which runs the opposite way
+	mx0, my0, mx1, my1, mInX, mInY := b.edgeLine(mate)
+	clearance := b.jointClearance()
+	slotLength := tabWidth + 2*clearance
+	slotWidth := b.slotWidth()
+	inset := b.Thickness + slotWidth/2
+
+	var slots pathbuilder.Path
+	for i := 0; i < j.Tabs; i++ {
+		t := 1 - tabWidth*(float64(2*i)+1)/length
+		cx := mx0 + (mx1-mx0)*t + mInX*inset
+		cy := my0 + (my1-my0)*t + mInY*inset
+		if mInY != 0 {
+			// Horizontal edge: slot runs left to right
+			slots.Subpaths = append(slots.Subpaths, pathbuilder.Rect(cx-slotLength/2, cy-slotWidth/2, slotLength, slotWidth))
+		} else {
+			slots.Subpaths = append(slots.Subpaths, pathbuilder.Rect(cx-slotWidth/2, cy-slotLength/2, slotWidth, slotLength))
+		}
+	}
+
+	return JointPaths{
+		Tabs:  tabs.Build().String(),
+		Folds: folds,
+		Slots: slots.String(),
+	}
+}
+
+// SeamEdge returns the edge the glue tab folds off, where the panel strip
+// is closed into a tube. A joint on it or its mate replaces the glue tab.
+func (b Box) SeamEdge() PanelEdge {
+	if b.GlueTab.Panel == GlueOnSideA {
+		return PanelEdge{SideA, LeftEdge}
+	}
+	return PanelEdge{Back, RightEdge}
+}
+
+// seamJoint returns the joint that closes the tube in place of the glue tab
+func (b Box) seamJoint() (Joint, bool) {
+	seam := b.SeamEdge()
+	for _, j := range b.Joints {
+		mate, _ := Mate(j.Edge)
+		if j.Edge == seam || mate == seam {
+			return j, true
+		}
+	}
+	return Joint{}, false
+}
+
+// freeEdge returns the end of the panel strip that is cut straight: the
+// mate of the glue tab's edge, or of the edge carrying the seam joint's tabs
+func (b Box) freeEdge() PanelEdge {
+	edge := b.SeamEdge()
+	if j, ok := b.seamJoint(); ok {
+		edge = j.Edge
+	}
+	mate, _ := Mate(edge)
+	return mate
+}
@@ -0,0 +1,67 @@
+package box
+
+import (
+	"math"
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// lengthOnEdge measures how much of a vertical panel edge the path's
+// straight segments run along
+func lengthOnEdge(t *testing.T, d string, b Box, pe PanelEdge) float64 {
+	t.Helper()
+	path, err := pathbuilder.ParsePath(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x0, y0, _, y1, _, _ := b.edgeLine(pe)
+	top, bottom := math.Min(y0, y1), math.Max(y0, y1)
+	var length float64
+	for _, sp := range path.Subpaths {
+		pos := sp.Start
+		for _, segment := range sp.Segments {
+			if segment.Op == pathbuilder.LineOp && math.Abs(pos.X-x0) < 0.01 && math.Abs(segment.End.X-x0) < 0.01 {
+				from, to := math.Min(pos.Y, segment.End.Y), math.Max(pos.Y, segment.End.Y)
+				length += math.Max(0, math.Min(to, bottom)-math.Max(from, top))
+			}
+			pos = segment.End
+		}
+	}
+	return length
+}
+
+func TestSeamJointFollowsGlueSide(t *testing.T) {
+	for _, side := range []GluePanel{GlueOnBack, GlueOnSideA} {
+		b := *NewBox(100, 40, 120, 0)
+		b.GlueTab.Panel = side
+		seam := b.SeamEdge()
+		b.Joints = []Joint{{Edge: seam, Tabs: 3}}
+		if err := (TuckEnd{}).Validate(b); err != nil {
+			t.Fatalf("glue side %d: %v", side, err)
+		}
+		paths, err := b.GenerateCompleteBox()
+		if err != nil {
+			t.Fatalf("glue side %d: %v", side, err)
+		}
+		if _, ok := paths["cut_glue_tab"]; ok {
+			t.Errorf("glue side %d: the joint should replace the glue tab", side)
+		}
+		// The tabbed edge is cut only round the tabs, never folded or cut
+		// straight across their roots
+		if l := lengthOnEdge(t, paths["fold_lines"], b, seam); l > 0 {
+			t.Errorf("glue side %d: %.1f mm of the %s is folded", side, l, seam)
+		}
+		if l := lengthOnEdge(t, paths["cut_lines"], b, seam); l > 0 {
+			t.Errorf("glue side %d: %.1f mm of the %s is cut straight", side, l, seam)
+		}
+		// The slotted edge it meets is cut free along its whole height
+		mate, _ := Mate(seam)
+		if l := lengthOnEdge(t, paths["cut_lines"], b, mate); math.Abs(l-b.Height) > 1 {
+			t.Errorf("glue side %d: %.1f mm of the %s is cut, want %.1f", side, l, mate, b.Height)
+		}
+		if l := lengthOnEdge(t, paths["fold_lines"], b, mate); l > 0 {
+			t.Errorf("glue side %d: %.1f mm of the %s is folded", side, l, mate)
+		}
+	}
+}
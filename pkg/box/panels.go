@@ -0,0 +1,128 @@
+package box
+
+import (
+	"fmt"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Panel names a face of the folded box
+type Panel int
+
+const (
+	SideA Panel = iota
+	Front
+	SideB
+	Back
+	// Lid is the top panel. The tuck closure hinges it on the front panel
+	// and the hinged lid style on the back panel.
+	Lid
+)
+
+func (p Panel) String() string {
+	switch p {
+	case SideA:
+		return "side A"
+	case Front:
+		return "front"
+	case SideB:
+		return "side B"
+	case Back:
+		return "back"
+	case Lid:
+		return "lid"
+	default:
+		return fmt.Sprintf("panel %d", int(p))
+	}
+}
+
+// Edge names a side of a panel as laid out on the sheet
+type Edge int
+
+const (
+	TopEdge Edge = iota
+	RightEdge
+	BottomEdge
+	LeftEdge
+)
+
+// PanelEdge is one edge of one panel
+type PanelEdge struct {
+	Panel Panel
+	Edge  Edge
+}
+
+func (pe PanelEdge) String() string {
+	return fmt.Sprintf("%s %s edge", pe.Panel, [...]string{"top", "right", "bottom", "left"}[pe.Edge])
+}
+
+// mates lists the free edges that meet once the box is folded. Edges joined
+// by a fold are already attached and never need a joint.
+var mates = map[PanelEdge]PanelEdge{
+	{Back, RightEdge}: {SideA, LeftEdge},
+	{Lid, TopEdge}:    {Back, TopEdge},
+	{Lid, LeftEdge}:   {SideA, TopEdge},
+	{Lid, RightEdge}:  {SideB, TopEdge},
+}
+
+// Mate returns the edge that meets pe when the box is folded
+func Mate(pe PanelEdge) (PanelEdge, bool) {
+	if mate, ok := mates[pe]; ok {
+		return mate, true
+	}
+	for a, b := range mates {
+		if b == pe {
+			return a, true
+		}
+	}
+	return PanelEdge{}, false
+}
+
+// PanelRect returns a panel's position and size on the sheet. The folds
+// between the panels are laid out on whole mm, so the panels are too. The
+// lid lies above the panel it is hinged on.
+func (b Box) PanelRect(p Panel) (x, y, width, height float64) {
+	edges := [...]int{b.SideALeft(), b.FrontPanelLeft(), b.FrontPanelRight(), b.BackPanelLeft(), b.BackPanelRight()}
+	// The tube's panels are declared in the order they run along the strip
+	span := func(i int) (float64, float64) {
+		return float64(edges[i]), float64(edges[i+1] - edges[i])
+	}
+	top := float64(b.Top())
+	switch p {
+	case SideA, Front, SideB, Back:
+		x, width = span(int(p))
+		return x, top, width, b.Height
+	case Lid:
+		hinge := Front
+		if b.lidOnBack {
+			hinge = Back
+		}
+		x, width = span(int(hinge))
+		return x, float64(b.Top() - b.D()), width, float64(b.D())
+	}
+	return 0, 0, 0, 0
+}
+
+// edgeLine returns the ends of a panel edge, running clockwise round the
+// panel on the sheet, and the unit vector pointing into the panel.
+//
+// Every panel is seen from the outside of the box, so two mating edges run
+// in opposite directions: a point a fraction t along one edge meets the
+// point 1-t along its mate.
+func (b Box) edgeLine(pe PanelEdge) (x0, y0, x1, y1, inX, inY float64) {
+	f := b.PanelFrame(pe.Panel)
+	var from, to Anchor
+	var in pathbuilder.Point
+	switch pe.Edge {
+	case TopEdge:
+		from, to, in = TopLeft, TopRight, pathbuilder.Point{X: 0, Y: 1}
+	case RightEdge:
+		from, to, in = TopRight, BottomRight, pathbuilder.Point{X: -1, Y: 0}
+	case BottomEdge:
+		from, to, in = BottomRight, BottomLeft, pathbuilder.Point{X: 0, Y: -1}
+	default:
+		from, to, in = BottomLeft, TopLeft, pathbuilder.Point{X: 1, Y: 0}
+	}
+	start, end, in := f.At(from), f.At(to), f.Vector(in)
+	return start.X, start.Y, end.X, end.Y, in.X, in.Y
+}
@@ -0,0 +1,87 @@
+package box
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Style generates the dielines for one kind of box from its dimensions.
+// Every style takes a Hanger except HingedLid, whose lid hinges along the
+// top of the back panel where the header would stand.
+type Style interface {
+	Name() string
+	// Validate reports why the box can't be made in this style
+	Validate(b Box) error
+	// Generate returns every piece of the box. Each dieline holds named
+	// layers, see LayerKind.
+	Generate(b Box) ([]Dieline, error)
+}
+
+// TuckEnd is the four panel tube with a tuck closure and crash-lock bottom
+type TuckEnd struct{}
+
+func (TuckEnd) Name() string { return "tuck" }
+
+func (TuckEnd) Validate(b Box) error {
+	return b.validateTube()
+}
+
+func (t TuckEnd) Generate(b Box) ([]Dieline, error) {
+	layers, err := b.GenerateCompleteBox()
+	if err != nil {
+		return nil, err
+	}
+	return []Dieline{b.BoxDieline(t.Name(), layers)}, nil
+}
+
+// validateTube checks the parts shared by every tube style
+func (b Box) validateTube() error {
+	if err := b.ValidateCrashLock(); err != nil {
+		return fmt.Errorf("bottom flaps will not lock: %w", err)
+	}
+	for _, j := range b.Joints {
+		if err := b.ValidateJoint(j); err != nil {
+			return err
+		}
+	}
+	for _, w := range b.Windows {
+		if err := b.ValidateWindow(w); err != nil {
+			return err
+		}
+	}
+	for _, a := range b.Artwork {
+		if err := b.ValidateArtwork(a); err != nil {
+			return err
+		}
+	}
+	if b.Hanger != nil {
+		if err := b.ValidateHanger(*b.Hanger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// styles lists the built in styles by name
+var styles = map[string]Style{
+	TuckEnd{}.Name():      TuckEnd{},
+	HingedLid{}.Name():    DefaultHingedLid(),
+	Telescoping{}.Name():  DefaultTelescoping(),
+	SleeveDrawer{}.Name(): DefaultSleeveDrawer(),
+}
+
+// StyleByName returns a built in style
+func StyleByName(name string) (Style, bool) {
+	style, ok := styles[name]
+	return style, ok
+}
+
+// StyleNames lists the built in styles in alphabetical order
+func StyleNames() []string {
+	names := make([]string, 0, len(styles))
+	for name := range styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,99 @@
+package box
+
+import (
+	"fmt"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Telescoping is a two piece box: an open tray as the base and a shallower
+// tray as the lid that slides down over it. The base is Width by Depth by
+// Height outside; the lid is sized from it.
+type Telescoping struct {
+	// LidDepth is how far the lid comes down over the base. Zero makes it a
+	// third of the height, the full Height gives a full telescope box.
+	LidDepth float64
+}
+
+// DefaultTelescoping has a lid a third of the box height
+func DefaultTelescoping() Telescoping {
+	return Telescoping{}
+}
+
+func (Telescoping) Name() string { return "telescoping" }
+
+func (t Telescoping) lidDepth(b Box) float64 {
+	if t.LidDepth > 0 {
+		return t.LidDepth
+	}
+	return b.Height / 3
+}
+
+// lidSize returns the lid's outside size. Inside it is the base's outside
+// plus clearance, so it slides on without binding.
+func (t Telescoping) lidSize(b Box) (width, depth, height float64) {
+	grow := 2*b.jointClearance() + 2*b.Thickness
+	return b.Width + grow, b.Depth + grow, t.lidDepth(b)
+}
+
+func (t Telescoping) Validate(b Box) error {
+	if len(b.Joints) > 0 || len(b.Windows) > 0 || len(b.Artwork) > 0 {
+		return fmt.Errorf("joints, windows and artwork need the panels of a tube style")
+	}
+	if t.LidDepth < 0 || t.lidDepth(b) > b.Height {
+		return fmt.Errorf("lid depth %.1f mm must be between 0 and the box height", t.LidDepth)
+	}
+	if b.Hanger != nil {
+		return b.validateHeader(*b.Hanger, b.Width)
+	}
+	return nil
+}
+
+// Generate returns the base and the lid as separate dielines. A hanger
+// stands on the base's back wall and comes up through a slot along the back
+// of the lid, so the base carries the weight and the lid still lifts off.
+func (t Telescoping) Generate(b Box) ([]Dieline, error) {
+	var err error
+	base := Dieline{Name: "base"}
+	var header []pathbuilder.Point
+	backWall := trayBackWall(b.Width, b.Depth, b.Height)
+	if b.Hanger != nil {
+		header = b.hangerPoints(backWall)
+	}
+	cuts, folds, width, height := trayLayout(b.Width, b.Depth, b.Height, header...)
+	layers := map[string]string{"cut_base": cuts, "fold_base": folds}
+	if b.Hanger != nil {
+		layers["fold_hanger"] = b.hangerFold(backWall)
+		layers["cut_hole_hanger"] = b.hangerHoles(backWall)
+		height += 2 * b.Hanger.Height
+	}
+	if base.Layers, err = b.applyKerf(layers); err != nil {
+		return nil, err
+	}
+	base.Width, base.Height = width, height
+
+	lid := Dieline{Name: "lid"}
+	lidWidth, lidDepth, lidWall := t.lidSize(b)
+	cuts, folds, width, height = trayLayout(lidWidth, lidDepth, lidWall)
+	layers = map[string]string{"cut_lid": cuts, "fold_lid": folds}
+	if b.Hanger != nil {
+		layers["cut_hole_lid"] = t.headerSlot(b).String()
+	}
+	if lid.Layers, err = b.applyKerf(layers); err != nil {
+		return nil, err
+	}
+	lid.Width, lid.Height = width, height
+
+	return []Dieline{base, lid}, nil
+}
+
+// headerSlot is the slot in the lid's top the hanger passes through. It
+// runs along the inside of the lid's back wall, as wide as the two layers
+// of the header with sliding clearance on both sides.
+func (t Telescoping) headerSlot(b Box) pathbuilder.Subpath {
+	width, depth, wall := t.lidSize(b)
+	clearance := b.jointClearance()
+	across := 2*b.Thickness + 2*clearance
+	back := wall + depth - b.Thickness
+	return pathbuilder.Rect(wall+width/2-b.Width/2-clearance, back-across, b.Width+2*clearance, across)
+}
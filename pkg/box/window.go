@@ -0,0 +1,127 @@
+package box
+
+import (
+	"fmt"
+	"math"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// WindowShape is the outline of a window cutout
+type WindowShape int
+
+const (
+	WindowRect WindowShape = iota
+	WindowRoundedRect
+	WindowCircle
+	WindowEllipse
+	// WindowPath cuts the SVG path data in Window.Path
+	WindowPath
+)
+
+func (s WindowShape) String() string {
+	switch s {
+	case WindowRoundedRect:
+		return "rounded"
+	case WindowCircle:
+		return "circle"
+	case WindowEllipse:
+		return "ellipse"
+	case WindowPath:
+		return "path"
+	default:
+		return "rect"
+	}
+}
+
+// Window is a display cutout in a panel. X and Y place its centre in the
+// panel's frame, see PanelFrame.
+type Window struct {
+	Panel  Panel
+	Shape  WindowShape
+	X, Y   float64
+	Width  float64
+	Height float64
+	// Radius rounds the corners of WindowRoundedRect
+	Radius float64
+	// Path is drawn around 0,0 and moved to X, Y. Width and Height are
+	// ignored, the path's own size is used.
+	Path string
+	// FilmMargin marks a band this wide round the window for gluing film on
+	// the inside. Zero leaves it out.
+	FilmMargin float64
+}
+
+// windowClearance keeps windows far enough from folds and edges that the
+// panel stays stiff and the crease doesn't run into the opening
+func (b Box) windowClearance() float64 {
+	return math.Max(5, 10*b.Thickness)
+}
+
+// windowShape returns the window in its panel's frame
+func (w Window) windowShape() (pathbuilder.Path, error) {
+	var outline pathbuilder.Subpath
+	switch w.Shape {
+	case WindowRect:
+		outline = pathbuilder.Rect(w.X-w.Width/2, w.Y-w.Height/2, w.Width, w.Height)
+	case WindowRoundedRect:
+		outline = pathbuilder.RoundedRect(w.X-w.Width/2, w.Y-w.Height/2, w.Width, w.Height, w.Radius)
+	case WindowCircle:
+		outline = pathbuilder.Ellipse(w.X, w.Y, w.Width/2, w.Width/2)
+	case WindowEllipse:
+		outline = pathbuilder.Ellipse(w.X, w.Y, w.Width/2, w.Height/2)
+	case WindowPath:
+		path, err := pathbuilder.ParsePath(w.Path)
+		if err != nil {
+			return pathbuilder.Path{}, err
+		}
+		if len(path.Subpaths) == 0 {
+			return pathbuilder.Path{}, fmt.Errorf("window path is empty")
+		}
+		for i := range path.Subpaths {
+			path.Subpaths[i].Closed = true
+		}
+		return path.Translate(w.X, w.Y), nil
+	default:
+		return pathbuilder.Path{}, fmt.Errorf("unknown window shape %d", int(w.Shape))
+	}
+	return pathbuilder.Path{Subpaths: []pathbuilder.Subpath{outline}}, nil
+}
+
+// ValidateWindow checks the window, and its film margin, keep clear of the
+// panel's folds and edges
+func (b Box) ValidateWindow(w Window) error {
+	if w.Shape != WindowPath && (w.Width <= 0 || (w.Shape != WindowCircle && w.Height <= 0)) {
+		return fmt.Errorf("%s window on the %s needs a size", w.Shape, w.Panel)
+	}
+	shape, err := w.windowShape()
+	if err != nil {
+		return fmt.Errorf("%s window on the %s: %w", w.Shape, w.Panel, err)
+	}
+	f := b.PanelFrame(w.Panel)
+	margin := math.Max(b.windowClearance(), w.FilmMargin)
+	for _, sp := range shape.Subpaths {
+		minX, minY, maxX, maxY := sp.Bounds()
+		if minX < margin || minY < margin || maxX > f.Width-margin || maxY > f.Height-margin {
+			return fmt.Errorf("%s window on the %s must stay %.1f mm from its folds and edges", w.Shape, w.Panel, margin)
+		}
+	}
+	return nil
+}
+
+// GenerateWindows returns the window cutouts and the film glue margins
+func (b Box) GenerateWindows() (cuts, film string) {
+	var cutPath, filmPath pathbuilder.Path
+	for _, w := range b.Windows {
+		shape, err := w.windowShape()
+		if err != nil {
+			continue
+		}
+		outline := b.PanelFrame(w.Panel).Path(shape)
+		cutPath.Subpaths = append(cutPath.Subpaths, outline.Subpaths...)
+		if w.FilmMargin > 0 {
+			filmPath.Subpaths = append(filmPath.Subpaths, outline.Offset(w.FilmMargin).Subpaths...)
+		}
+	}
+	return cutPath.String(), filmPath.String()
+}
@@ -0,0 +1,39 @@
+package box
+
+import (
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+func TestLidWindowFollowsHinge(t *testing.T) {
+	b := *NewBox(100, 60, 120, 0)
+	b.Windows = []Window{{Panel: Lid, Shape: WindowRect, X: 50, Y: 30, Width: 30, Height: 20}}
+	styles := []struct {
+		style Style
+		hinge Panel
+	}{
+		{TuckEnd{}, Front},
+		{DefaultHingedLid(), Back},
+	}
+	for _, tc := range styles {
+		if err := tc.style.Validate(b); err != nil {
+			t.Fatalf("%s: %v", tc.style.Name(), err)
+		}
+		dielines, err := tc.style.Generate(b)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.style.Name(), err)
+		}
+		window, err := pathbuilder.ParsePath(dielines[0].Layers["cut_hole_windows"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		minX, minY, maxX, maxY := window.Bounds()
+		x, y, _, _ := b.PanelRect(tc.hinge)
+		wantX, wantY := x+35, y-b.Depth+20
+		if minX != wantX || minY != wantY || maxX != wantX+30 || maxY != wantY+20 {
+			t.Errorf("%s: window at %v,%v to %v,%v, want it on the lid above the %s from %v,%v",
+				tc.style.Name(), minX, minY, maxX, maxY, tc.hinge, wantX, wantY)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package gcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+	"42clients.com/puzzlebox/pkg/toolpath"
+)
+
+// Tool describes one of the heads on the machine
+type Tool struct {
+	Number int
+	// Depth is the Z height while the tool is engaged, negative into the board
+	Depth float64
+}
+
+// Config controls the generated program. All lengths are in mm.
+type Config struct {
+	Header []string
+	Footer []string
+
+	Knife     Tool // used for cut lines
+	Crease    Tool // used for fold lines
+	Perforate Tool // used for perforations
+
+	SafeZ      float64
+	FeedRate   float64 // mm/min while cutting or creasing
+	PlungeRate float64 // mm/min while lowering the tool
+
+	// Overcut continues closed knife cuts past their start so the blade
+	// fully separates the last corner.
+	Overcut float64
+
+	// LiftAngle is the turn in degrees above which the tool is lifted and
+	// rotated in the air instead of being dragged round the corner.
+	LiftAngle float64
+
+	// Tolerance is the maximum chord error when curves are flattened
+	Tolerance float64
+
+	// SheetHeight flips Y so the program uses a bottom-left origin.
+	// Zero keeps the SVG orientation.
+	SheetHeight float64
+}
+
+// DefaultConfig returns settings for a tangential knife on T1 and a
+// creasing wheel on T2, with a perforating wheel on T3.
+func DefaultConfig() Config {
+	return Config{
+		Header: []string{
+			"G21 (millimetres)",
+			"G90 (absolute positioning)",
+			"G17",
+		},
+		Footer: []string{
+			"M5",
+			"M30",
+		},
+		Knife:      Tool{Number: 1, Depth: -0.5},
+		Crease:     Tool{Number: 2, Depth: -0.3},
+		Perforate:  Tool{Number: 3, Depth: -0.5},
+		SafeZ:      5,
+		FeedRate:   1500,
+		PlungeRate: 300,
+		Overcut:    1,
+		LiftAngle:  30,
+		Tolerance:  0.1,
+	}
+}
+
+// Write renders the plan as a G-code program. Strokes are written in plan
+// order, changing tools only when the line kind changes.
+func Write(w io.Writer, plan toolpath.Plan, cfg Config) error {
+	bw := bufio.NewWriter(w)
+	p := &program{w: bw, cfg: cfg, tool: -1}
+
+	for _, line := range cfg.Header {
+		p.line("%s", line)
+	}
+	p.line("G0 Z%s", num(cfg.SafeZ))
+
+	for _, stroke := range plan.Strokes {
+		p.stroke(stroke)
+	}
+
+	p.line("G0 Z%s", num(cfg.SafeZ))
+	for _, line := range cfg.Footer {
+		p.line("%s", line)
+	}
+
+	if p.err != nil {
+		return p.err
+	}
+	return bw.Flush()
+}
+
+type program struct {
+	w       *bufio.Writer
+	cfg     Config
+	tool    int
+	heading float64
+	err     error
+}
+
+func (p *program) line(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format+"\n", args...)
+}
+
+// toolFor maps a line kind onto the machine head that handles it
+func (p *program) toolFor(kind box.LineKind) Tool {
+	switch kind {
+	case box.Crease:
+		return p.cfg.Crease
+	case box.Perforation:
+		return p.cfg.Perforate
+	}
+	return p.cfg.Knife
+}
+
+func (p *program) stroke(stroke toolpath.Stroke) {
+	tool := p.toolFor(stroke.Kind)
+	if tool.Number != p.tool {
+		// Every stroke ends at SafeZ so the change can happen straight away
+		p.line("T%d M6 (%s)", tool.Number, stroke.Kind)
+		p.tool = tool.Number
+	}
+
+	points := p.transform(stroke.Path.Flatten(p.cfg.Tolerance))
+	if stroke.Kind == box.Cut && stroke.Path.IsClosed() {
+		points = overcut(points, p.cfg.Overcut)
+	}
+	points = dedupe(points)
+	if len(points) < 2 {
+		return
+	}
+
+	p.line("(%s)", stroke.Layer)
+	p.line("G0 X%s Y%s", num(points[0].X), num(points[0].Y))
+	p.rotate(heading(points[0], points[1]))
+	p.line("G1 Z%s F%s", num(tool.Depth), num(p.cfg.PlungeRate))
+
+	for i := 1; i < len(points); i++ {
+		next := unwrap(heading(points[i-1], points[i]), p.heading)
+		if math.Abs(next-p.heading) > p.cfg.LiftAngle {
+			// Lift, turn the blade in the air and plunge again
+			p.line("G0 Z%s", num(p.cfg.SafeZ))
+			p.rotate(next)
+			p.line("G1 Z%s F%s", num(tool.Depth), num(p.cfg.PlungeRate))
+		} else {
+			p.heading = next
+		}
+		p.line("G1 X%s Y%s C%s F%s", num(points[i].X), num(points[i].Y), num(p.heading), num(p.cfg.FeedRate))
+	}
+	p.line("G0 Z%s", num(p.cfg.SafeZ))
+}
+
+// rotate turns the tool to face the given heading, taking the short way round
+func (p *program) rotate(angle float64) {
+	p.heading = unwrap(angle, p.heading)
+	p.line("G0 C%s", num(p.heading))
+}
+
+func (p *program) transform(points []pathbuilder.Point) []pathbuilder.Point {
+	if p.cfg.SheetHeight == 0 {
+		return points
+	}
+	flipped := make([]pathbuilder.Point, len(points))
+	for i, pt := range points {
+		flipped[i] = pathbuilder.Point{X: pt.X, Y: p.cfg.SheetHeight - pt.Y}
+	}
+	return flipped
+}
+
+// heading returns the direction of travel in degrees
+func heading(from, to pathbuilder.Point) float64 {
+	return math.Atan2(to.Y-from.Y, to.X-from.X) * 180 / math.Pi
+}
+
+// unwrap picks the equivalent of angle closest to previous so the C axis
+// never spins a full turn between segments.
+func unwrap(angle, previous float64) float64 {
+	for angle-previous > 180 {
+		angle -= 360
+	}
+	for angle-previous < -180 {
+		angle += 360
+	}
+	return angle
+}
+
+// overcut extends a closed polyline along its own start by length
+func overcut(points []pathbuilder.Point, length float64) []pathbuilder.Point {
+	if length <= 0 || len(points) < 2 {
+		return points
+	}
+	extended := append([]pathbuilder.Point{}, points...)
+	for i := 1; i < len(points) && length > 0; i++ {
+		a, b := points[i-1], points[i]
+		d := a.Dist(b)
+		if d >= length {
+			t := length / d
+			extended = append(extended, pathbuilder.Point{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t})
+			break
+		}
+		extended = append(extended, b)
+		length -= d
+	}
+	return extended
+}
+
+// dedupe drops consecutive repeated points, which have no heading
+func dedupe(points []pathbuilder.Point) []pathbuilder.Point {
+	var result []pathbuilder.Point
+	for _, pt := range points {
+		if len(result) > 0 && result[len(result)-1].Dist(pt) < 1e-9 {
+			continue
+		}
+		result = append(result, pt)
+	}
+	return result
+}
+
+// num formats a value with at most three decimals
+func num(v float64) string {
+	v = math.Round(v*1000) / 1000
+	if v == 0 {
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,164 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+	"42clients.com/puzzlebox/pkg/toolpath"
+)
+
+// polyline returns an open stroke on a layer through the points
+func polyline(layer string, points ...pathbuilder.Point) toolpath.Stroke {
+	sp := pathbuilder.Subpath{Start: points[0]}
+	for _, p := range points[1:] {
+		sp.Segments = append(sp.Segments, pathbuilder.Segment{Op: pathbuilder.LineOp, End: p})
+	}
+	return toolpath.Stroke{Layer: layer, Kind: box.LayerKind(layer), Path: sp}
+}
+
+// checkProgram compares the program written for the strokes with the
+// expected lines
+func checkProgram(t *testing.T, strokes []toolpath.Stroke, cfg Config, want string) {
+	t.Helper()
+	var sb strings.Builder
+	if err := Write(&sb, toolpath.Plan{Strokes: strokes}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	want = strings.TrimLeft(want, "\n")
+	if got := sb.String(); got != want {
+		t.Errorf("got program:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteToolDepths(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Knife.Depth = -0.8
+	cfg.Crease.Depth = -0.2
+	strokes := []toolpath.Stroke{
+		polyline("fold_lines", pathbuilder.Point{X: 0, Y: 5}, pathbuilder.Point{X: 10, Y: 5}),
+		polyline("perf_tear", pathbuilder.Point{X: 10, Y: 7}, pathbuilder.Point{X: 0, Y: 7}),
+		polyline("cut_lines", pathbuilder.Point{X: 0, Y: 10}, pathbuilder.Point{X: 10, Y: 10}),
+		polyline("cut_lines", pathbuilder.Point{X: 10, Y: 12}, pathbuilder.Point{X: 0, Y: 12}),
+	}
+	checkProgram(t, strokes, cfg, `
+G21 (millimetres)
+G90 (absolute positioning)
+G17
+G0 Z5
+T2 M6 (crease)
+(fold_lines)
+G0 X0 Y5
+G0 C0
+G1 Z-0.2 F300
+G1 X10 Y5 C0 F1500
+G0 Z5
+T3 M6 (perforation)
+(perf_tear)
+G0 X10 Y7
+G0 C180
+G1 Z-0.5 F300
+G1 X0 Y7 C180 F1500
+G0 Z5
+T1 M6 (cut)
+(cut_lines)
+G0 X0 Y10
+G0 C0
+G1 Z-0.8 F300
+G1 X10 Y10 C0 F1500
+G0 Z5
+(cut_lines)
+G0 X10 Y12
+G0 C180
+G1 Z-0.8 F300
+G1 X0 Y12 C180 F1500
+G0 Z5
+G0 Z5
+M5
+M30
+`)
+}
+
+func TestWriteOvercutAndLift(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Overcut = 3
+	strokes := []toolpath.Stroke{
+		// Closed: every corner turns 90 degrees and the cut runs on past
+		// the start
+		{Layer: "cut_hole", Kind: box.Cut, Path: pathbuilder.Rect(0, 0, 10, 4)},
+		// Open: the 17 degree bend stays down, the 107 degree one lifts
+		polyline("cut_lines", pathbuilder.Point{X: 0, Y: 10}, pathbuilder.Point{X: 10, Y: 10},
+			pathbuilder.Point{X: 20, Y: 13}, pathbuilder.Point{X: 20, Y: 0}),
+	}
+	checkProgram(t, strokes, cfg, `
+G21 (millimetres)
+G90 (absolute positioning)
+G17
+G0 Z5
+T1 M6 (cut)
+(cut_hole)
+G0 X0 Y0
+G0 C0
+G1 Z-0.5 F300
+G1 X10 Y0 C0 F1500
+G0 Z5
+G0 C90
+G1 Z-0.5 F300
+G1 X10 Y4 C90 F1500
+G0 Z5
+G0 C180
+G1 Z-0.5 F300
+G1 X0 Y4 C180 F1500
+G0 Z5
+G0 C270
+G1 Z-0.5 F300
+G1 X0 Y0 C270 F1500
+G0 Z5
+G0 C360
+G1 Z-0.5 F300
+G1 X3 Y0 C360 F1500
+G0 Z5
+(cut_lines)
+G0 X0 Y10
+G0 C360
+G1 Z-0.5 F300
+G1 X10 Y10 C360 F1500
+G1 X20 Y13 C376.699 F1500
+G0 Z5
+G0 C270
+G1 Z-0.5 F300
+G1 X20 Y0 C270 F1500
+G0 Z5
+G0 Z5
+M5
+M30
+`)
+}
+
+func TestWriteHeaderAndFooter(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Header = []string{"%", "O1000 (BOX)", "G21"}
+	cfg.Footer = []string{"M2", "%"}
+	cfg.SafeZ = 10
+	cfg.SheetHeight = 20
+	strokes := []toolpath.Stroke{
+		polyline("cut_lines", pathbuilder.Point{X: 0, Y: 0}, pathbuilder.Point{X: 10, Y: 0}),
+	}
+	checkProgram(t, strokes, cfg, `
+%
+O1000 (BOX)
+G21
+G0 Z10
+T1 M6 (cut)
+(cut_lines)
+G0 X0 Y20
+G0 C0
+G1 Z-0.5 F300
+G1 X10 Y20 C0 F1500
+G0 Z10
+G0 Z10
+M2
+%
+`)
+}
@@ -0,0 +1,101 @@
+package hpgl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+	"42clients.com/puzzlebox/pkg/toolpath"
+)
+
+// UnitsPerMM is the HPGL plotter unit (0.025 mm)
+const UnitsPerMM = 40
+
+// Config controls the generated job
+type Config struct {
+	// Pens selects the pen (or tool holder) for each line kind. Every kind
+	// in the plan needs one; pen 0 puts the pen away rather than drawing.
+	Pens map[box.LineKind]int
+
+	// Tolerance is the maximum chord error in mm when curves are flattened
+	Tolerance float64
+
+	// SheetHeight flips Y so the plot uses the plotter's bottom-left origin.
+	// Zero keeps the SVG orientation.
+	SheetHeight float64
+}
+
+// DefaultConfig cuts with pen 1, creases with pen 2 and perforates with pen 3
+func DefaultConfig() Config {
+	return Config{
+		Pens: map[box.LineKind]int{
+			box.Cut:         1,
+			box.Crease:      2,
+			box.Perforation: 3,
+		},
+		Tolerance: 0.1,
+	}
+}
+
+// Write renders the plan as an HPGL job, selecting a new pen only when the
+// line kind changes. A plan with a line kind that has no pen is rejected
+// before anything is written.
+func Write(w io.Writer, plan toolpath.Plan, cfg Config) error {
+	for _, stroke := range plan.Strokes {
+		if _, ok := cfg.Pens[stroke.Kind]; !ok {
+			return fmt.Errorf("no pen selected for %s lines", stroke.Kind)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	pen := -1
+
+	fmt.Fprint(bw, "IN;\n")
+	for _, stroke := range plan.Strokes {
+		points := stroke.Path.Flatten(cfg.Tolerance)
+		if len(points) < 2 {
+			continue
+		}
+
+		if p := cfg.Pens[stroke.Kind]; p != pen {
+			fmt.Fprintf(bw, "SP%d;\n", p)
+			pen = p
+		}
+
+		start := cfg.units(points[0])
+		fmt.Fprintf(bw, "PU%d,%d;\n", start[0], start[1])
+
+		coords := make([]string, 0, len(points)-1)
+		last := start
+		for _, pt := range points[1:] {
+			u := cfg.units(pt)
+			if u == last {
+				continue
+			}
+			coords = append(coords, fmt.Sprintf("%d,%d", u[0], u[1]))
+			last = u
+		}
+		if len(coords) > 0 {
+			fmt.Fprintf(bw, "PD%s;\n", strings.Join(coords, ","))
+		}
+	}
+	fmt.Fprint(bw, "PU;\nSP0;\n")
+
+	return bw.Flush()
+}
+
+// units converts a sheet position in mm to plotter units
+func (cfg Config) units(pt pathbuilder.Point) [2]int {
+	y := pt.Y
+	if cfg.SheetHeight != 0 {
+		y = cfg.SheetHeight - y
+	}
+	return [2]int{
+		int(math.Round(pt.X * UnitsPerMM)),
+		int(math.Round(y * UnitsPerMM)),
+	}
+}
@@ -0,0 +1,94 @@
+package hpgl
+
+import (
+	"strings"
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+	"42clients.com/puzzlebox/pkg/toolpath"
+)
+
+// polyline returns an open stroke on a layer through the points
+func polyline(layer string, points ...pathbuilder.Point) toolpath.Stroke {
+	sp := pathbuilder.Subpath{Start: points[0]}
+	for _, p := range points[1:] {
+		sp.Segments = append(sp.Segments, pathbuilder.Segment{Op: pathbuilder.LineOp, End: p})
+	}
+	return toolpath.Stroke{Layer: layer, Kind: box.LayerKind(layer), Path: sp}
+}
+
+// checkJob compares the job written for the strokes with the expected lines
+func checkJob(t *testing.T, strokes []toolpath.Stroke, cfg Config, want string) {
+	t.Helper()
+	var sb strings.Builder
+	if err := Write(&sb, toolpath.Plan{Strokes: strokes}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	want = strings.TrimLeft(want, "\n")
+	if got := sb.String(); got != want {
+		t.Errorf("got job:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWritePensByKind(t *testing.T) {
+	strokes := []toolpath.Stroke{
+		polyline("fold_lines", pathbuilder.Point{X: 0, Y: 5}, pathbuilder.Point{X: 10, Y: 5}),
+		polyline("fold_flaps", pathbuilder.Point{X: 10, Y: 6}, pathbuilder.Point{X: 0, Y: 6}),
+		polyline("perf_tear", pathbuilder.Point{X: 0, Y: 7}, pathbuilder.Point{X: 10, Y: 7}),
+		{Layer: "cut_hole", Kind: box.Cut, Path: pathbuilder.Rect(0, 0, 10, 4)},
+	}
+	checkJob(t, strokes, DefaultConfig(), `
+IN;
+SP2;
+PU0,200;
+PD400,200;
+PU400,240;
+PD0,240;
+SP3;
+PU0,280;
+PD400,280;
+SP1;
+PU0,0;
+PD400,0,400,160,0,160,0,0;
+PU;
+SP0;
+`)
+}
+
+func TestWriteCustomPensAndFlip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Pens = map[box.LineKind]int{box.Cut: 4, box.Crease: 4}
+	cfg.SheetHeight = 20
+	strokes := []toolpath.Stroke{
+		polyline("fold_lines", pathbuilder.Point{X: 0, Y: 5}, pathbuilder.Point{X: 10, Y: 5}),
+		polyline("cut_lines", pathbuilder.Point{X: 0, Y: 0}, pathbuilder.Point{X: 2.5, Y: 0}),
+	}
+	checkJob(t, strokes, cfg, `
+IN;
+SP4;
+PU0,600;
+PD400,600;
+PU0,800;
+PD100,800;
+PU;
+SP0;
+`)
+}
+
+func TestWriteRejectsKindWithoutPen(t *testing.T) {
+	cfg := DefaultConfig()
+	delete(cfg.Pens, box.Perforation)
+	strokes := []toolpath.Stroke{
+		polyline("cut_lines", pathbuilder.Point{X: 0, Y: 0}, pathbuilder.Point{X: 10, Y: 0}),
+		polyline("perf_tear", pathbuilder.Point{X: 0, Y: 7}, pathbuilder.Point{X: 10, Y: 7}),
+	}
+	var sb strings.Builder
+	err := Write(&sb, toolpath.Plan{Strokes: strokes}, cfg)
+	if err == nil || !strings.Contains(err.Error(), "perforation") {
+		t.Errorf("got error %v, want one naming the perforation lines", err)
+	}
+	if sb.Len() > 0 {
+		t.Errorf("wrote %q before rejecting the plan", sb.String())
+	}
+}
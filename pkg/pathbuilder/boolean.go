@@ -0,0 +1,306 @@
+package pathbuilder
+
+import (
+	"math"
+	"sort"
+)
+
+// booleanTolerance is how closely curves are followed while working out
+// the outlines of a boolean operation
+const booleanTolerance = 0.01
+
+// snapDistance merges points closer than this, so edges shared by both
+// operands and corners that only touch are treated as the same
+const snapDistance = 1e-6
+
+// Union returns the area covered by either path. Each path is read with
+// its own fill rule and every subpath is taken as closed. The result is
+// made of closed subpaths drawn clockwise on the sheet, with holes drawn
+// anticlockwise; curves that survive the operation are kept as curves.
+func (p Path) Union(q Path) Path {
+	return boolean(p, q, func(inP, inQ bool) bool { return inP || inQ })
+}
+
+// Difference returns the area of the path that the other path doesn't
+// cover, see Union
+func (p Path) Difference(q Path) Path {
+	return boolean(p, q, func(inP, inQ bool) bool { return inP && !inQ })
+}
+
+// Intersection returns the area covered by both paths, see Union
+func (p Path) Intersection(q Path) Path {
+	return boolean(p, q, func(inP, inQ bool) bool { return inP && inQ })
+}
+
+// source is the segment an edge was flattened from
+type source struct {
+	p0      Point
+	segment Segment
+}
+
+// booleanEdge is a straight piece of an operand's outline, running from t0
+// to t1 along its source segment
+type booleanEdge struct {
+	p0, p1 Point
+	src    *source
+	t0, t1 float64
+}
+
+// fragment is an edge cut at every crossing, between two snapped vertices
+type fragment struct {
+	from, to int
+	src      *source
+	t0, t1   float64
+}
+
+// boolean keeps the edges of both operands that separate the result from
+// the rest of the sheet. Every edge is cut where anything crosses or
+// touches it, the pieces are deduplicated so shared edges appear once, and
+// each piece is kept when the result is inside on exactly one side.
+func boolean(p, q Path, inside func(inP, inQ bool) bool) Path {
+	ringsP, edgesP := booleanOutline(p)
+	ringsQ, edgesQ := booleanOutline(q)
+	edges := append(edgesP, edgesQ...)
+
+	// Vertices are filed in a grid of snapDistance cells, so a point only
+	// needs comparing with those in its own cell and the ones around it
+	var vertices []Point
+	grid := make(map[[2]int64][]int)
+	vertex := func(pt Point) int {
+		cx, cy := int64(math.Floor(pt.X/snapDistance)), int64(math.Floor(pt.Y/snapDistance))
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for _, i := range grid[[2]int64{cx + dx, cy + dy}] {
+					if vertices[i].Dist(pt) < snapDistance {
+						return i
+					}
+				}
+			}
+		}
+		vertices = append(vertices, pt)
+		grid[[2]int64{cx, cy}] = append(grid[[2]int64{cx, cy}], len(vertices)-1)
+		return len(vertices) - 1
+	}
+
+	cuts := splitEdges(edges)
+	seen := make(map[[2]int]bool)
+	var kept []fragment
+	for i, e := range edges {
+		params := append([]float64{0, 1}, cuts[i]...)
+		sort.Float64s(params)
+		for k := 1; k < len(params); k++ {
+			u0, u1 := params[k-1], params[k]
+			f := fragment{
+				from: vertex(lerp(e.p0, e.p1, u0)),
+				to:   vertex(lerp(e.p0, e.p1, u1)),
+				src:  e.src,
+				t0:   e.t0 + u0*(e.t1-e.t0),
+				t1:   e.t0 + u1*(e.t1-e.t0),
+			}
+			key := [2]int{min(f.from, f.to), max(f.from, f.to)}
+			if f.from == f.to || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			// Look just to either side of the middle of the piece
+			a, b := vertices[f.from], vertices[f.to]
+			mid := midpoint(a, b)
+			side := scale(rightNormal(unit(sub(b, a))), math.Min(1e-4, a.Dist(b)/4))
+			right, left := add(mid, side), sub(mid, side)
+			inRight := inside(p.FillRule.contains(ringsP, right), q.FillRule.contains(ringsQ, right))
+			inLeft := inside(p.FillRule.contains(ringsP, left), q.FillRule.contains(ringsQ, left))
+			switch {
+			case inRight && !inLeft:
+				kept = append(kept, f)
+			case inLeft && !inRight:
+				kept = append(kept, fragment{from: f.to, to: f.from, src: f.src, t0: f.t1, t1: f.t0})
+			}
+		}
+	}
+
+	return linkFragments(kept, vertices)
+}
+
+// booleanOutline flattens a path into closed rings for inside tests and
+// the edges along them
+func booleanOutline(p Path) ([][]Point, []booleanEdge) {
+	var rings [][]Point
+	var edges []booleanEdge
+	for _, sp := range p.Subpaths {
+		segments := sp.Segments
+		if sp.End().Dist(sp.Start) > snapDistance {
+			segments = append(append([]Segment{}, segments...), Segment{Op: LineOp, End: sp.Start})
+		}
+		var ring []Point
+		pos := sp.Start
+		for _, segment := range segments {
+			src := &source{p0: pos, segment: segment}
+			points, params := flattenSegment(pos, segment, booleanTolerance)
+			from, t0 := pos, 0.0
+			for i, pt := range points {
+				edges = append(edges, booleanEdge{p0: from, p1: pt, src: src, t0: t0, t1: params[i]})
+				ring = append(ring, from)
+				from, t0 = pt, params[i]
+			}
+			pos = segment.End
+		}
+		if len(ring) > 2 {
+			rings = append(rings, ring)
+		}
+	}
+	return rings, edges
+}
+
+// splitEdges returns, for every edge, the positions along it where other
+// edges cross it, touch it or start to run along it
+func splitEdges(edges []booleanEdge) [][]float64 {
+	cuts := make([][]float64, len(edges))
+	const end = 1e-9
+	add := func(i int, u float64) {
+		if u > end && u < 1-end {
+			cuts[i] = append(cuts[i], u)
+		}
+	}
+	for i := range edges {
+		p, r := edges[i].p0, sub(edges[i].p1, edges[i].p0)
+		for j := i + 1; j < len(edges); j++ {
+			q, s := edges[j].p0, sub(edges[j].p1, edges[j].p0)
+			lengths := math.Hypot(r.X, r.Y) * math.Hypot(s.X, s.Y)
+			if lengths == 0 {
+				continue
+			}
+			denom := cross(r, s)
+			if math.Abs(denom) < 1e-12*lengths {
+				// Parallel: only collinear edges overlap, and cut each
+				// other at the ends of the overlap
+				if math.Abs(cross(sub(q, p), r)) > snapDistance*math.Hypot(r.X, r.Y) {
+					continue
+				}
+				add(i, dot(sub(q, p), r)/dot(r, r))
+				add(i, dot(sub(edges[j].p1, p), r)/dot(r, r))
+				add(j, dot(sub(p, q), s)/dot(s, s))
+				add(j, dot(sub(edges[i].p1, q), s)/dot(s, s))
+				continue
+			}
+			u := cross(sub(q, p), s) / denom
+			v := cross(sub(q, p), r) / denom
+			if u < -end || u > 1+end || v < -end || v > 1+end {
+				continue
+			}
+			add(i, u)
+			add(j, v)
+		}
+	}
+	return cuts
+}
+
+// linkFragments joins the kept pieces into closed rings. Where rings touch
+// at a corner the sharpest right turn is taken, which keeps them apart.
+func linkFragments(fragments []fragment, vertices []Point) Path {
+	outgoing := make(map[int][]int)
+	for i, f := range fragments {
+		outgoing[f.from] = append(outgoing[f.from], i)
+	}
+	used := make([]bool, len(fragments))
+
+	var result Path
+	for first := range fragments {
+		if used[first] {
+			continue
+		}
+		var ring []fragment
+		for current := first; current >= 0; {
+			used[current] = true
+			f := fragments[current]
+			ring = append(ring, f)
+			if f.to == fragments[first].from {
+				break
+			}
+			heading := sub(vertices[f.to], vertices[f.from])
+			next, best := -1, math.Inf(-1)
+			for _, candidate := range outgoing[f.to] {
+				if used[candidate] {
+					continue
+				}
+				d := sub(vertices[fragments[candidate].to], vertices[f.to])
+				if turn := math.Atan2(cross(heading, d), dot(heading, d)); turn > best {
+					next, best = candidate, turn
+				}
+			}
+			current = next
+		}
+		if len(ring) > 2 {
+			result.Subpaths = append(result.Subpaths, ringSubpath(ring, vertices))
+		}
+	}
+	return result
+}
+
+// ringSubpath rebuilds a ring's segments. Runs of pieces cut from the same
+// curve become that part of the curve again, and straight pieces in line
+// with each other are joined.
+func ringSubpath(ring []fragment, vertices []Point) Subpath {
+	continues := func(a, b fragment) bool {
+		return a.src == b.src && math.Abs(a.t1-b.t0) < 1e-9
+	}
+	// Start at a corner so no run wraps round the end of the ring
+	for k := range ring {
+		if !continues(ring[(k+len(ring)-1)%len(ring)], ring[k]) {
+			ring = append(append([]fragment{}, ring[k:]...), ring[:k]...)
+			break
+		}
+	}
+
+	sp := Subpath{Start: vertices[ring[0].from], Closed: true}
+	for i := 0; i < len(ring); {
+		j := i
+		for j+1 < len(ring) && continues(ring[j], ring[j+1]) {
+			j++
+		}
+		start, end := vertices[ring[i].from], vertices[ring[j].to]
+		segment := Segment{Op: LineOp, End: end}
+		if src := ring[i].src; src.segment.Op != LineOp {
+			from, to := ring[i].t0, ring[j].t1
+			reversed := from > to
+			if reversed {
+				from, to = to, from
+			}
+			segment = trimSegment(src.p0, src.segment, from, to)
+			if reversed {
+				part := Subpath{Start: src.segment.PointAt(src.p0, from), Segments: []Segment{segment}}
+				segment = part.Reverse().Segments[0]
+			}
+			segment.End = end
+		}
+
+		// A straight piece carrying on in the same direction extends the last
+		if last := len(sp.Segments) - 1; segment.Op == LineOp && last >= 0 && sp.Segments[last].Op == LineOp {
+			before := sp.Start
+			if last > 0 {
+				before = sp.Segments[last-1].End
+			}
+			in, out := sub(start, before), sub(end, start)
+			if math.Abs(cross(unit(in), unit(out))) < 1e-9 && dot(in, out) > 0 {
+				sp.Segments[last].End = end
+				i = j + 1
+				continue
+			}
+		}
+		sp.Segments = append(sp.Segments, segment)
+		i = j + 1
+	}
+	// Z draws the line back to the start, and takes in the first line when
+	// the two run on in the same direction
+	if last := len(sp.Segments) - 1; last >= 0 && sp.Segments[last].Op == LineOp && sp.Segments[last].End.Dist(sp.Start) < snapDistance {
+		sp.Segments = sp.Segments[:last]
+	}
+	if len(sp.Segments) > 2 && sp.Segments[0].Op == LineOp {
+		in, out := sub(sp.Start, sp.End()), sub(sp.Segments[0].End, sp.Start)
+		if math.Abs(cross(unit(in), unit(out))) < 1e-9 && dot(in, out) > 0 {
+			sp.Start = sp.Segments[0].End
+			sp.Segments = sp.Segments[1:]
+		}
+	}
+	return sp
+}
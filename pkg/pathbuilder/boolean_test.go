@@ -0,0 +1,85 @@
+package pathbuilder
+
+import (
+	"math"
+	"testing"
+)
+
+// area is the area a boolean result encloses: outlines are drawn clockwise
+// on the sheet and holes anticlockwise, so their signed areas add up
+func area(p Path) float64 {
+	total := 0.0
+	for _, sp := range p.Subpaths {
+		points := sp.Flatten(1e-4)
+		for i := range points {
+			a, b := points[i], points[(i+1)%len(points)]
+			total += a.X*b.Y - b.X*a.Y
+		}
+	}
+	return total / 2
+}
+
+func square(x, y, size float64) Path {
+	return Path{Subpaths: []Subpath{Rect(x, y, size, size)}}
+}
+
+func TestBooleanOperations(t *testing.T) {
+	// A 30 mm square with a 10 mm square hole, drawn the other way round
+	ring := Path{Subpaths: []Subpath{Rect(0, 0, 30, 30), Rect(10, 10, 10, 10).Reverse()}}
+	// A circle of radius 5 centred on the right edge of a 20 mm square
+	circle := Path{Subpaths: []Subpath{{
+		Start: Point{25, 10},
+		Segments: []Segment{
+			{Op: ArcOp, Radii: Point{5, 5}, Sweep: true, End: Point{15, 10}},
+			{Op: ArcOp, Radii: Point{5, 5}, Sweep: true, End: Point{25, 10}},
+		},
+		Closed: true,
+	}}}
+
+	tests := []struct {
+		name     string
+		result   Path
+		area     float64
+		subpaths int
+	}{
+		{"union sharing an edge", square(0, 0, 10).Union(square(10, 0, 10)), 200, 1},
+		{"union sharing part of an edge", square(0, 0, 10).Union(square(10, 5, 10)), 200, 1},
+		{"difference sharing an edge", square(0, 0, 10).Difference(square(10, 0, 10)), 100, 1},
+		{"intersection sharing an edge", square(0, 0, 10).Intersection(square(10, 0, 10)), 0, 0},
+		{"union touching at a corner", square(0, 0, 10).Union(square(10, 10, 10)), 200, 2},
+		{"intersection touching at a corner", square(0, 0, 10).Intersection(square(10, 10, 10)), 0, 0},
+		{"union of identical squares", square(0, 0, 10).Union(square(0, 0, 10)), 100, 1},
+		{"intersection of identical squares", square(0, 0, 10).Intersection(square(0, 0, 10)), 100, 1},
+		{"difference of identical squares", square(0, 0, 10).Difference(square(0, 0, 10)), 0, 0},
+		{"union with an island in the hole", ring.Union(square(12, 12, 6)), 800 + 36, 3},
+		{"union filling the hole", ring.Union(square(10, 10, 10)), 900, 1},
+		{"difference widening the hole", ring.Difference(square(5, 5, 20)), 500, 2},
+		{"intersection across the hole", ring.Intersection(square(0, 0, 15)), 225 - 25, 1},
+		{"difference of a circle", square(0, 0, 20).Difference(circle), 400 - 12.5*math.Pi, 1},
+		{"union with a circle", square(0, 0, 20).Union(circle), 400 + 12.5*math.Pi, 1},
+		{"intersection with a circle", square(0, 0, 20).Intersection(circle), 12.5 * math.Pi, 1},
+	}
+	for _, tc := range tests {
+		if got := len(tc.result.Subpaths); got != tc.subpaths {
+			t.Errorf("%s: %d subpaths, want %d: %s", tc.name, got, tc.subpaths, tc.result)
+		}
+		if got := area(tc.result); math.Abs(got-tc.area) > 1e-3 {
+			t.Errorf("%s: area %.4f, want %.4f: %s", tc.name, got, tc.area, tc.result)
+		}
+	}
+}
+
+func TestBooleanKeepsCurves(t *testing.T) {
+	circle := Path{Subpaths: []Subpath{Ellipse(20, 10, 5, 5)}}
+	result := square(0, 0, 20).Difference(circle)
+	curves := 0
+	for _, segment := range result.Subpaths[0].Segments {
+		if segment.Op != LineOp {
+			curves++
+		}
+	}
+	// Half of the eight quadratics lie inside the square
+	if curves != 4 {
+		t.Errorf("%d curves kept, want 4: %s", curves, result)
+	}
+}
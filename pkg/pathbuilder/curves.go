@@ -0,0 +1,195 @@
+package pathbuilder
+
+import "math"
+
+// ellipseArc is an SVG arc in centre form: the ellipse and the range of
+// angles it is drawn over
+type ellipseArc struct {
+	centre Point
+	rx, ry float64
+	// phi is the rotation of the x radius in radians
+	phi    float64
+	theta0 float64
+	delta  float64
+}
+
+// arcCenter converts an arc from its SVG end point form, following the
+// SVG implementation notes. Radii too small to reach the end point are
+// scaled up. It reports false for arcs drawn as straight lines: zero radii
+// or an end point on the start.
+func arcCenter(p0 Point, s Segment) (ellipseArc, bool) {
+	rx, ry := math.Abs(s.Radii.X), math.Abs(s.Radii.Y)
+	if rx == 0 || ry == 0 || p0.Dist(s.End) < 1e-9 {
+		return ellipseArc{}, false
+	}
+	phi := s.Rotation * math.Pi / 180
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+
+	hx, hy := (p0.X-s.End.X)/2, (p0.Y-s.End.Y)/2
+	x1 := cosPhi*hx + sinPhi*hy
+	y1 := -sinPhi*hx + cosPhi*hy
+
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if s.LargeArc == s.Sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+
+	centre := Point{
+		X: cosPhi*cx1 - sinPhi*cy1 + (p0.X+s.End.X)/2,
+		Y: sinPhi*cx1 + cosPhi*cy1 + (p0.Y+s.End.Y)/2,
+	}
+	theta0 := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	theta1 := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx)
+	delta := theta1 - theta0
+	if s.Sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !s.Sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	return ellipseArc{centre: centre, rx: rx, ry: ry, phi: phi, theta0: theta0, delta: delta}, true
+}
+
+func (a ellipseArc) at(theta float64) Point {
+	sinPhi, cosPhi := math.Sin(a.phi), math.Cos(a.phi)
+	x, y := a.rx*math.Cos(theta), a.ry*math.Sin(theta)
+	return Point{a.centre.X + cosPhi*x - sinPhi*y, a.centre.Y + sinPhi*x + cosPhi*y}
+}
+
+// derivative is the rate of change of the point with theta
+func (a ellipseArc) derivative(theta float64) Point {
+	sinPhi, cosPhi := math.Sin(a.phi), math.Cos(a.phi)
+	x, y := -a.rx*math.Sin(theta), a.ry*math.Cos(theta)
+	return Point{cosPhi*x - sinPhi*y, sinPhi*x + cosPhi*y}
+}
+
+// PointAt returns the point at parameter t (0 to 1) along the segment drawn
+// from p0. Arcs are parameterised by angle.
+func (s Segment) PointAt(p0 Point, t float64) Point {
+	mt := 1 - t
+	switch s.Op {
+	case QuadOp:
+		return Point{
+			X: mt*mt*p0.X + 2*mt*t*s.Ctrl.X + t*t*s.End.X,
+			Y: mt*mt*p0.Y + 2*mt*t*s.Ctrl.Y + t*t*s.End.Y,
+		}
+	case CubicOp:
+		return Point{
+			X: mt*mt*mt*p0.X + 3*mt*mt*t*s.Ctrl.X + 3*mt*t*t*s.Ctrl2.X + t*t*t*s.End.X,
+			Y: mt*mt*mt*p0.Y + 3*mt*mt*t*s.Ctrl.Y + 3*mt*t*t*s.Ctrl2.Y + t*t*t*s.End.Y,
+		}
+	case ArcOp:
+		if a, ok := arcCenter(p0, s); ok {
+			return a.at(a.theta0 + t*a.delta)
+		}
+	}
+	return Point{mt*p0.X + t*s.End.X, mt*p0.Y + t*s.End.Y}
+}
+
+// TangentAt returns the unit direction of travel at parameter t. Where a
+// curve's control point sits on its end the direction comes from the
+// neighbouring control points.
+func (s Segment) TangentAt(p0 Point, t float64) Point {
+	d := s.derivativeAt(p0, t)
+	if d.Dist(Point{}) < 1e-9 {
+		switch s.Op {
+		case QuadOp:
+			d = sub(s.End, p0)
+		case CubicOp:
+			if t < 0.5 {
+				d = sub(s.Ctrl2, p0)
+			} else {
+				d = sub(s.End, s.Ctrl)
+			}
+		}
+	}
+	return unit(d)
+}
+
+// derivativeAt is the rate of change of the point with t, so its length is
+// the speed the segment is drawn at
+func (s Segment) derivativeAt(p0 Point, t float64) Point {
+	mt := 1 - t
+	switch s.Op {
+	case QuadOp:
+		return scale(add(scale(sub(s.Ctrl, p0), mt), scale(sub(s.End, s.Ctrl), t)), 2)
+	case CubicOp:
+		d := add(add(scale(sub(s.Ctrl, p0), mt*mt), scale(sub(s.Ctrl2, s.Ctrl), 2*mt*t)), scale(sub(s.End, s.Ctrl2), t*t))
+		return scale(d, 3)
+	case ArcOp:
+		if a, ok := arcCenter(p0, s); ok {
+			return scale(a.derivative(a.theta0+t*a.delta), a.delta)
+		}
+	}
+	return sub(s.End, p0)
+}
+
+// Split cuts the segment drawn from p0 at parameter t into two segments
+// that draw the same curve
+func (s Segment) Split(p0 Point, t float64) (Segment, Segment) {
+	mid := s.PointAt(p0, t)
+	switch s.Op {
+	case QuadOp:
+		c0 := lerp(p0, s.Ctrl, t)
+		c1 := lerp(s.Ctrl, s.End, t)
+		return Segment{Op: QuadOp, Ctrl: c0, End: mid}, Segment{Op: QuadOp, Ctrl: c1, End: s.End}
+	case CubicOp:
+		ab, bc, cd := lerp(p0, s.Ctrl, t), lerp(s.Ctrl, s.Ctrl2, t), lerp(s.Ctrl2, s.End, t)
+		abc, bcd := lerp(ab, bc, t), lerp(bc, cd, t)
+		return Segment{Op: CubicOp, Ctrl: ab, Ctrl2: abc, End: mid},
+			Segment{Op: CubicOp, Ctrl: bcd, Ctrl2: cd, End: s.End}
+	case ArcOp:
+		a, ok := arcCenter(p0, s)
+		if !ok {
+			break
+		}
+		first, second := s, s
+		// The radii may have been scaled up to reach the end point
+		first.Radii, second.Radii = Point{a.rx, a.ry}, Point{a.rx, a.ry}
+		first.End = mid
+		first.LargeArc = math.Abs(t*a.delta) > math.Pi
+		second.LargeArc = math.Abs((1-t)*a.delta) > math.Pi
+		return first, second
+	}
+	return Segment{Op: LineOp, End: mid}, Segment{Op: LineOp, End: s.End}
+}
+
+// arcToCubics approximates an arc with cubics of at most a quarter turn
+func arcToCubics(p0 Point, s Segment) []Segment {
+	a, ok := arcCenter(p0, s)
+	if !ok {
+		return []Segment{{Op: LineOp, End: s.End}}
+	}
+	count := int(math.Ceil(math.Abs(a.delta) / (math.Pi / 2)))
+	step := a.delta / float64(count)
+	// Control arm length for a circular arc of angle step
+	k := 4.0 / 3 * math.Tan(step/4)
+
+	var cubics []Segment
+	for i := 0; i < count; i++ {
+		from := a.theta0 + float64(i)*step
+		to := from + step
+		end := a.at(to)
+		if i == count-1 {
+			end = s.End
+		}
+		cubics = append(cubics, Segment{
+			Op:    CubicOp,
+			Ctrl:  add(a.at(from), scale(a.derivative(from), k)),
+			Ctrl2: sub(a.at(to), scale(a.derivative(to), k)),
+			End:   end,
+		})
+	}
+	return cubics
+}
+
+func lerp(a, b Point, t float64) Point {
+	return Point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t}
+}
@@ -0,0 +1,243 @@
+package pathbuilder
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprFunctions are the functions an expression may call
+var exprFunctions = map[string]func(args []float64) (float64, error){
+	"min": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("min needs at least one argument")
+		}
+		result := args[0]
+		for _, a := range args[1:] {
+			result = math.Min(result, a)
+		}
+		return result, nil
+	},
+	"max": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("max needs at least one argument")
+		}
+		result := args[0]
+		for _, a := range args[1:] {
+			result = math.Max(result, a)
+		}
+		return result, nil
+	},
+	"abs": func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("abs needs one argument")
+		}
+		return math.Abs(args[0]), nil
+	},
+	"sqrt": func(args []float64) (float64, error) {
+		if len(args) != 1 || args[0] < 0 {
+			return 0, fmt.Errorf("sqrt needs one argument that isn't negative")
+		}
+		return math.Sqrt(args[0]), nil
+	},
+}
+
+// Eval works out an arithmetic expression such as "H - 2*FoldGap" or
+// "min(D, W)/2". It knows +, -, *, / and brackets, numbers, the named
+// values in vars and the functions min, max, abs and sqrt.
+func Eval(expr string, vars map[string]float64) (float64, error) {
+	p := &exprParser{s: expr, vars: vars}
+	v, err := p.sum()
+	if err != nil {
+		return 0, fmt.Errorf("%q: %w", expr, err)
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return 0, fmt.Errorf("%q: unexpected %q", expr, p.s[p.pos:])
+	}
+	return v, nil
+}
+
+// EvalPath reads path data whose numbers may be expressions in braces,
+// e.g. "M0,0 L{D*0.5},{SideFlapHeight/2}". Each expression is evaluated
+// with Eval and the result parsed with ParsePath, so a shape kept as a
+// template follows the values it refers to.
+func EvalPath(d string, vars map[string]float64) (Path, error) {
+	var sb strings.Builder
+	rest := d
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return Path{}, fmt.Errorf("expression %q is missing its closing brace", rest[open:])
+		}
+		v, err := Eval(rest[open+1:open+end], vars)
+		if err != nil {
+			return Path{}, err
+		}
+		// Spaces keep the value apart from the numbers around it
+		sb.WriteString(rest[:open])
+		sb.WriteString(" " + strconv.FormatFloat(v, 'f', -1, 64) + " ")
+		rest = rest[open+end+1:]
+	}
+	return ParsePath(sb.String())
+}
+
+// exprParser evaluates an expression by recursive descent as it reads it
+type exprParser struct {
+	s    string
+	pos  int
+	vars map[string]float64
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next character that isn't a space, or 0 at the end
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// sum reads terms joined by + and -
+func (p *exprParser) sum() (float64, error) {
+	v, err := p.product()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return v, nil
+		}
+		p.pos++
+		rhs, err := p.product()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			v += rhs
+		} else {
+			v -= rhs
+		}
+	}
+}
+
+// product reads factors joined by * and /
+func (p *exprParser) product() (float64, error) {
+	v, err := p.factor()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return v, nil
+		}
+		p.pos++
+		rhs, err := p.factor()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			v *= rhs
+		} else if rhs == 0 {
+			return 0, fmt.Errorf("division by zero")
+		} else {
+			v /= rhs
+		}
+	}
+}
+
+// factor reads a signed number, name, function call or bracketed sum
+func (p *exprParser) factor() (float64, error) {
+	switch c := p.peek(); {
+	case c == '-' || c == '+':
+		p.pos++
+		v, err := p.factor()
+		if c == '-' {
+			v = -v
+		}
+		return v, err
+	case c == '(':
+		p.pos++
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing bracket")
+		}
+		p.pos++
+		return v, nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", p.s[start:p.pos])
+		}
+		return v, nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] == '_' || unicode.IsLetter(rune(p.s[p.pos])) || unicode.IsDigit(rune(p.s[p.pos]))) {
+			p.pos++
+		}
+		name := p.s[start:p.pos]
+		if p.peek() == '(' {
+			return p.call(name)
+		}
+		v, ok := p.vars[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown name %q", name)
+		}
+		return v, nil
+	case c == 0:
+		return 0, fmt.Errorf("expression ends too soon")
+	default:
+		return 0, fmt.Errorf("unexpected %q", string(c))
+	}
+}
+
+// call reads the bracketed arguments of a function and applies it
+func (p *exprParser) call(name string) (float64, error) {
+	fn, ok := exprFunctions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+	p.pos++ // the opening bracket
+	var args []float64
+	if p.peek() == ')' {
+		p.pos++
+		return fn(args)
+	}
+	for {
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, v)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return fn(args)
+		default:
+			return 0, fmt.Errorf("%s is missing its closing bracket", name)
+		}
+	}
+}
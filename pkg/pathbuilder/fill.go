@@ -0,0 +1,56 @@
+package pathbuilder
+
+// FillRule decides which areas a path with several subpaths encloses
+type FillRule int
+
+const (
+	// NonZero fills where the subpaths wind round a point at all, so a hole
+	// must wind the other way to the outline around it. It is SVG's default.
+	NonZero FillRule = iota
+	// EvenOdd fills where a point is inside an odd number of subpaths
+	EvenOdd
+)
+
+// String returns the rule as used by the SVG fill-rule attribute
+func (r FillRule) String() string {
+	if r == EvenOdd {
+		return "evenodd"
+	}
+	return "nonzero"
+}
+
+// Contains reports whether the path encloses a point by its fill rule.
+// Every subpath is taken as closed and curves are followed to within
+// booleanTolerance.
+func (p Path) Contains(pt Point) bool {
+	var rings [][]Point
+	for _, sp := range p.Subpaths {
+		rings = append(rings, sp.Flatten(booleanTolerance))
+	}
+	return p.FillRule.contains(rings, pt)
+}
+
+// contains tests a point against closed polygons
+func (r FillRule) contains(rings [][]Point, pt Point) bool {
+	winding := 0
+	for _, ring := range rings {
+		for i := range ring {
+			a, b := ring[i], ring[(i+1)%len(ring)]
+			if (a.Y > pt.Y) == (b.Y > pt.Y) {
+				continue
+			}
+			// Only crossings to the right of the point count, up or down
+			if pt.X < a.X+(pt.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+				if b.Y > a.Y {
+					winding++
+				} else {
+					winding--
+				}
+			}
+		}
+	}
+	if r == EvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
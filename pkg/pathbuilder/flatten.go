@@ -0,0 +1,121 @@
+package pathbuilder
+
+import "math"
+
+// maxFlattenDepth caps how often a curve is halved, so a curve can't
+// become more than 65536 chords however small the tolerance
+const maxFlattenDepth = 16
+
+// maxFlattenAngle is the widest turn of an arc a single chord may cover
+const maxFlattenAngle = math.Pi / 4
+
+// Flatten converts the subpath into a polyline for backends that only draw
+// straight lines. Curves are halved until every chord stays within
+// tolerance of the curve it replaces, so tight corners get more points than
+// gentle sweeps. A tolerance of zero or less keeps only the end points of
+// Béziers and cuts arcs every 45°, which is enough to tell which way a
+// shape winds. Closed subpaths end with their start point.
+func (sp Subpath) Flatten(tolerance float64) []Point {
+	points := []Point{sp.Start}
+	pos := sp.Start
+	for _, segment := range sp.Segments {
+		flat, _ := flattenSegment(pos, segment, tolerance)
+		points = append(points, flat...)
+		pos = segment.End
+	}
+	if sp.Closed && pos.Dist(sp.Start) > 0 {
+		points = append(points, sp.Start)
+	}
+	return points
+}
+
+// Flatten converts every subpath into a polyline, see Subpath.Flatten
+func (p Path) Flatten(tolerance float64) [][]Point {
+	polylines := make([][]Point, len(p.Subpaths))
+	for i, sp := range p.Subpaths {
+		polylines[i] = sp.Flatten(tolerance)
+	}
+	return polylines
+}
+
+// flattenSegment returns the points after p0 of a segment's polyline and
+// the parameter of the segment at each of them
+func flattenSegment(p0 Point, s Segment, tolerance float64) ([]Point, []float64) {
+	if s.Op == LineOp {
+		return []Point{s.End}, []float64{1}
+	}
+	if s.Op == ArcOp {
+		a, ok := arcCenter(p0, s)
+		if !ok {
+			return []Point{s.End}, []float64{1}
+		}
+		if tolerance <= 0 {
+			steps := int(math.Ceil(math.Abs(a.delta) / maxFlattenAngle))
+			points := make([]Point, steps)
+			params := make([]float64, steps)
+			for i := range steps {
+				params[i] = float64(i+1) / float64(steps)
+				points[i] = a.at(a.theta0 + a.delta*params[i])
+			}
+			points[steps-1] = s.End
+			return points, params
+		}
+	} else if tolerance <= 0 {
+		return []Point{s.End}, []float64{1}
+	}
+
+	var points []Point
+	var params []float64
+	// Halving a segment at t = 0.5 halves its parameter range too, since
+	// Béziers split by parameter and arcs by angle
+	var subdivide func(start Point, part Segment, t0, t1 float64, depth int)
+	subdivide = func(start Point, part Segment, t0, t1 float64, depth int) {
+		if depth == maxFlattenDepth || chordError(start, part) <= tolerance {
+			points = append(points, part.End)
+			params = append(params, t1)
+			return
+		}
+		first, second := part.Split(start, 0.5)
+		mid := (t0 + t1) / 2
+		subdivide(start, first, t0, mid, depth+1)
+		subdivide(first.End, second, mid, t1, depth+1)
+	}
+	subdivide(p0, s, 0, 1, 0)
+	points[len(points)-1] = s.End
+	return points, params
+}
+
+// chordError bounds how far a curve strays from the chord between its ends.
+// A Bézier stays in the hull of its control points, weighted by at most 1/2
+// for a quadratic's control and 3/4 for a cubic's two together. An arc
+// turning less than maxFlattenAngle bulges furthest in the middle.
+func chordError(p0 Point, s Segment) float64 {
+	switch s.Op {
+	case QuadOp:
+		return distToSegment(s.Ctrl, p0, s.End) / 2
+	case CubicOp:
+		return 0.75 * math.Max(distToSegment(s.Ctrl, p0, s.End), distToSegment(s.Ctrl2, p0, s.End))
+	case ArcOp:
+		a, ok := arcCenter(p0, s)
+		if !ok {
+			return 0
+		}
+		if math.Abs(a.delta) > maxFlattenAngle {
+			return math.Inf(1)
+		}
+		return distToSegment(a.at(a.theta0+a.delta/2), p0, s.End)
+	}
+	return 0
+}
+
+// distToSegment is the distance from p to the nearest point of the line
+// from a to b
+func distToSegment(p, a, b Point) float64 {
+	ab := sub(b, a)
+	length := dot(ab, ab)
+	if length == 0 {
+		return p.Dist(a)
+	}
+	t := math.Max(0, math.Min(1, dot(sub(p, a), ab)/length))
+	return p.Dist(add(a, scale(ab, t)))
+}
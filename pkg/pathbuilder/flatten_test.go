@@ -0,0 +1,36 @@
+package pathbuilder
+
+import "testing"
+
+func TestFlattenDeviation(t *testing.T) {
+	p0 := Point{0, 0}
+	segments := map[string]Segment{
+		"quad":        {Op: QuadOp, Ctrl: Point{50, 120}, End: Point{100, 0}},
+		"cubic":       {Op: CubicOp, Ctrl: Point{0, 100}, Ctrl2: Point{100, -100}, End: Point{100, 0}},
+		"rotated arc": {Op: ArcOp, Radii: Point{80, 30}, Rotation: 30, LargeArc: true, Sweep: true, End: Point{60, 40}},
+	}
+	const samples = 200
+	for name, s := range segments {
+		for _, tolerance := range []float64{1, 0.1, 0.01} {
+			points, params := flattenSegment(p0, s, tolerance)
+			if last := points[len(points)-1]; last != s.End {
+				t.Errorf("%s at %g: ends at %v, want %v", name, tolerance, last, s.End)
+			}
+			// Every stretch of the curve stays within tolerance of the chord
+			// that replaces it
+			from, t0 := p0, 0.0
+			worst := 0.0
+			for i, to := range points {
+				t1 := params[i]
+				for k := 0; k <= samples; k++ {
+					on := s.PointAt(p0, t0+(t1-t0)*float64(k)/samples)
+					worst = max(worst, distToSegment(on, from, to))
+				}
+				from, t0 = to, t1
+			}
+			if worst > tolerance*1.001 {
+				t.Errorf("%s at %g: deviates by %.4g over %d chords", name, tolerance, worst, len(points))
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package pathbuilder
+
+import "math"
+
+// Frame places local coordinates on the sheet. Points are turned Rotation
+// quarter turns clockwise about the local origin, then moved to Origin, so
+// a feature can be drawn once and placed on any panel.
+type Frame struct {
+	Origin   Point
+	Rotation int
+}
+
+// Vector turns a direction or offset into sheet space
+func (f Frame) Vector(v Point) Point {
+	switch ((f.Rotation % 4) + 4) % 4 {
+	case 1:
+		return Point{-v.Y, v.X}
+	case 2:
+		return Point{-v.X, -v.Y}
+	case 3:
+		return Point{v.Y, -v.X}
+	default:
+		return v
+	}
+}
+
+// Apply maps a local point onto the sheet
+func (f Frame) Apply(p Point) Point {
+	return add(f.Origin, f.Vector(p))
+}
+
+// Local maps a sheet point back into the frame
+func (f Frame) Local(p Point) Point {
+	return Frame{Rotation: -f.Rotation}.Vector(sub(p, f.Origin))
+}
+
+// Path maps every point of a path drawn in local coordinates onto the sheet
+func (f Frame) Path(p Path) Path {
+	mapped := Path{FillRule: p.FillRule}
+	for _, sp := range p.Subpaths {
+		moved := Subpath{Start: f.Apply(sp.Start), Closed: sp.Closed}
+		for _, segment := range sp.Segments {
+			segment.Ctrl = f.Apply(segment.Ctrl)
+			segment.Ctrl2 = f.Apply(segment.Ctrl2)
+			segment.End = f.Apply(segment.End)
+			segment.Rotation += 90 * float64(f.Rotation)
+			moved.Segments = append(moved.Segments, segment)
+		}
+		mapped.Subpaths = append(mapped.Subpaths, moved)
+	}
+	return mapped
+}
+
+// sheetInt maps a local point for the integer builder
+func (f Frame) sheetInt(x, y int) (int, int) {
+	p := f.Apply(Point{float64(x), float64(y)})
+	return int(math.Round(p.X)), int(math.Round(p.Y))
+}
+
+// vectorInt maps a local offset for the integer builder
+func (f Frame) vectorInt(x, y int) (int, int) {
+	v := f.Vector(Point{float64(x), float64(y)})
+	return int(math.Round(v.X)), int(math.Round(v.Y))
+}
@@ -0,0 +1,117 @@
+package pathbuilder
+
+import "math"
+
+// measureTolerance is how closely curves are followed when measuring them
+const measureTolerance = 0.001
+
+// Length returns the length of the segment drawn from p0. Lines and
+// circular arcs are measured exactly; Béziers and elliptical arcs are
+// integrated numerically to well under a micron.
+func (s Segment) Length(p0 Point) float64 {
+	switch s.Op {
+	case LineOp:
+		return p0.Dist(s.End)
+	case ArcOp:
+		a, ok := arcCenter(p0, s)
+		if !ok {
+			return p0.Dist(s.End)
+		}
+		if math.Abs(a.rx-a.ry) <= 1e-12*a.rx {
+			return a.rx * math.Abs(a.delta)
+		}
+	}
+	speed := func(t float64) float64 {
+		d := s.derivativeAt(p0, t)
+		return math.Hypot(d.X, d.Y)
+	}
+	return integrate(speed, 0, 1, gaussLegendre(speed, 0, 1), 16)
+}
+
+// Length returns the distance the pen travels along the subpath, including
+// the line a Z draws back to the start
+func (sp Subpath) Length() float64 {
+	total := 0.0
+	pos := sp.Start
+	for _, segment := range sp.Segments {
+		total += segment.Length(pos)
+		pos = segment.End
+	}
+	if sp.Closed {
+		total += pos.Dist(sp.Start)
+	}
+	return total
+}
+
+// Length returns the total length of all subpaths. Moves between subpaths
+// are not counted.
+func (p Path) Length() float64 {
+	total := 0.0
+	for _, sp := range p.Subpaths {
+		total += sp.Length()
+	}
+	return total
+}
+
+// PointAtLength returns the point the given distance along the subpath.
+// Distances past either end give the end.
+func (sp Subpath) PointAtLength(distance float64) Point {
+	points := sp.Flatten(measureTolerance)
+	for i := 1; i < len(points); i++ {
+		step := points[i-1].Dist(points[i])
+		if distance <= step {
+			if step == 0 {
+				return points[i]
+			}
+			return lerp(points[i-1], points[i], max(distance, 0)/step)
+		}
+		distance -= step
+	}
+	return points[len(points)-1]
+}
+
+// PointAtLength returns the point the given distance along the path,
+// counting only the subpaths themselves. Distances past either end give the
+// end. An empty path gives the origin.
+func (p Path) PointAtLength(distance float64) Point {
+	for i, sp := range p.Subpaths {
+		length := sp.Length()
+		if distance <= length || i == len(p.Subpaths)-1 {
+			return sp.PointAtLength(distance)
+		}
+		distance -= length
+	}
+	return Point{}
+}
+
+func polylineLength(points []Point) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += points[i-1].Dist(points[i])
+	}
+	return total
+}
+
+// integrate refines a Gauss-Legendre estimate of f over a to b by halving
+// the interval until the halves agree with the whole
+func integrate(f func(float64) float64, a, b, whole float64, depth int) float64 {
+	mid := (a + b) / 2
+	left, right := gaussLegendre(f, a, mid), gaussLegendre(f, mid, b)
+	if depth == 0 || math.Abs(left+right-whole) < 1e-9 {
+		return left + right
+	}
+	return integrate(f, a, mid, left, depth-1) + integrate(f, mid, b, right, depth-1)
+}
+
+// gaussLegendre integrates f over a to b with five points, which is exact
+// for polynomials up to degree nine
+func gaussLegendre(f func(float64) float64, a, b float64) float64 {
+	nodes := [5]float64{0, -0.5384693101056831, 0.5384693101056831, -0.9061798459386640, 0.9061798459386640}
+	weights := [5]float64{0.5688888888888889, 0.4786286704993665, 0.4786286704993665, 0.2369268850561891, 0.2369268850561891}
+	half, centre := (b-a)/2, (a+b)/2
+	total := 0.0
+	for i, x := range nodes {
+		total += weights[i] * f(centre+half*x)
+	}
+	return total * half
+}
@@ -0,0 +1,338 @@
+package pathbuilder
+
+import "math"
+
+// miterLimit caps how far a sharp corner may be extended, as a multiple of
+// the offset distance, before it is bevelled instead.
+const miterLimit = 4.0
+
+// edge is a segment with its start point spelled out
+type edge struct {
+	op    SegmentOp
+	p0    Point
+	ctrl  Point
+	ctrl2 Point
+	p1    Point
+}
+
+func (e edge) startTangent() Point {
+	if e.op != LineOp {
+		return e.segment().TangentAt(e.p0, 0)
+	}
+	return unit(sub(e.p1, e.p0))
+}
+
+func (e edge) endTangent() Point {
+	if e.op != LineOp {
+		return e.segment().TangentAt(e.p0, 1)
+	}
+	return unit(sub(e.p1, e.p0))
+}
+
+func (e edge) segment() Segment {
+	return Segment{Op: e.op, Ctrl: e.ctrl, Ctrl2: e.ctrl2, End: e.p1}
+}
+
+// Offset moves the subpath sideways by distance. Closed contours grow for a
+// positive distance and shrink for a negative one, whichever way they were
+// drawn. Open subpaths are treated as an outline closed by the straight line
+// between their ends, which is how a flap hangs off its fold, and move away
+// from that outline. A straight open line moves to its right on the sheet.
+//
+// Lines are shifted along their normal, fillets are offset by moving their
+// control polygon so they stay tangent to the neighbouring lines, concave
+// corners are trimmed to the intersection of the offset edges and convex
+// corners are mitred (or bevelled when the miter would be too long).
+func (sp Subpath) Offset(distance float64) Subpath {
+	if distance == 0 || len(sp.Segments) == 0 {
+		return sp
+	}
+
+	closed := sp.IsClosed()
+	edges := sp.edges()
+	if closed && sp.End().Dist(sp.Start) > 1e-9 {
+		edges = append(edges, edge{op: LineOp, p0: sp.End(), p1: sp.Start})
+	}
+
+	side := distance
+	if sp.signedArea() > 0 {
+		// Clockwise on the sheet: the outside is on the left
+		side = -distance
+	}
+
+	var shifted []edge
+	for _, e := range edges {
+		if e.p0.Dist(e.p1) < 1e-9 && e.op == LineOp {
+			continue
+		}
+		o := offsetEdge(e, side)
+		// A fillet tighter than the offset turns inside out; the corner is
+		// rebuilt from its neighbours instead.
+		if e.op != LineOp && dot(sub(o.p1, o.p0), sub(e.p1, e.p0)) <= 0 {
+			continue
+		}
+		shifted = append(shifted, o)
+	}
+	if len(shifted) == 0 {
+		return sp
+	}
+
+	joined := joinEdges(shifted, closed, math.Abs(distance))
+
+	result := Subpath{Start: joined[0].p0, Closed: sp.Closed}
+	for i, e := range joined {
+		if i > 0 && joined[i-1].p1.Dist(e.p0) > 1e-9 {
+			result.Segments = append(result.Segments, Segment{Op: LineOp, End: e.p0})
+		}
+		result.Segments = append(result.Segments, e.segment())
+	}
+	if closed && !sp.Closed && result.End().Dist(result.Start) > 1e-9 {
+		result.Segments = append(result.Segments, Segment{Op: LineOp, End: result.Start})
+	}
+	return result
+}
+
+// Offset moves every subpath of the path, see Subpath.Offset
+func (p Path) Offset(distance float64) Path {
+	result := Path{Subpaths: make([]Subpath, len(p.Subpaths)), FillRule: p.FillRule}
+	for i, sp := range p.Subpaths {
+		result.Subpaths[i] = sp.Offset(distance)
+	}
+	return result
+}
+
+// OffsetPath offsets SVG path data, for callers still working with strings
+func OffsetPath(d string, distance float64) (string, error) {
+	path, err := ParsePath(d)
+	if err != nil {
+		return "", err
+	}
+	return path.Offset(distance).String(), nil
+}
+
+// OffsetTogether offsets paths that draw one outline between them, such as
+// the cut layers of a piece, keeping each path separate. Open subpaths that
+// meet end to end within a path are joined first, see JoinEnds. Where an
+// open end in one path meets just one open end in another, the two offset
+// ends are mitred to where their lines cross, as a corner inside a subpath
+// would be.
+func OffsetTogether(paths []Path, distance, tolerance float64) []Path {
+	type end struct {
+		path, subpath int
+		atStart       bool
+	}
+	joined := make([]Path, len(paths))
+	result := make([]Path, len(paths))
+	var ends []end
+	for i, p := range paths {
+		joined[i] = p.JoinEnds(tolerance)
+		result[i] = joined[i].Offset(distance)
+		for j, sp := range joined[i].Subpaths {
+			if !sp.IsClosed() && len(sp.Segments) > 0 {
+				ends = append(ends, end{i, j, true}, end{i, j, false})
+			}
+		}
+	}
+	at := func(e end) Point {
+		sp := joined[e.path].Subpaths[e.subpath]
+		if e.atStart {
+			return sp.Start
+		}
+		return sp.End()
+	}
+
+	for a := range ends {
+		var meets []int
+		for b := range ends {
+			if b != a && at(ends[a]).Dist(at(ends[b])) <= tolerance {
+				meets = append(meets, b)
+			}
+		}
+		// Where three or more ends meet there is no one corner to mitre
+		if len(meets) != 1 || meets[0] < a {
+			continue
+		}
+		b := ends[meets[0]]
+		mitreEnds(&result[ends[a].path].Subpaths[ends[a].subpath], ends[a].atStart,
+			&result[b.path].Subpaths[b.subpath], b.atStart, math.Abs(distance))
+	}
+	return result
+}
+
+// mitreEnds moves an end of each of two offset subpaths to where their end
+// lines cross. Ends that would have to move further than a mitre may, or a
+// curve that would have to be cut short, are left apart.
+func mitreEnds(a *Subpath, aStart bool, b *Subpath, bStart bool, distance float64) {
+	pa, da := a.outward(aStart)
+	pb, db := b.outward(bStart)
+	if pa.Dist(pb) < 1e-9 {
+		return
+	}
+	corner, ok := intersectLines(pa, da, pb, db)
+	if !ok || corner.Dist(midpoint(pa, pb)) > miterLimit*distance {
+		return
+	}
+	if !a.canMoveEnd(aStart, corner) || !b.canMoveEnd(bStart, corner) {
+		return
+	}
+	a.moveEnd(aStart, corner)
+	b.moveEnd(bStart, corner)
+}
+
+// outward returns the start or end point and the direction pointing out of
+// the subpath there
+func (sp Subpath) outward(atStart bool) (Point, Point) {
+	if atStart {
+		return sp.Start, scale(sp.Segments[0].TangentAt(sp.Start, 0), -1)
+	}
+	last := len(sp.Segments) - 1
+	before := sp.Start
+	if last > 0 {
+		before = sp.Segments[last-1].End
+	}
+	return sp.End(), sp.Segments[last].TangentAt(before, 1)
+}
+
+// endLine returns the far point of the first or last segment, and whether
+// that segment is a straight line
+func (sp Subpath) endLine(atStart bool) (Point, bool) {
+	if atStart {
+		return sp.Segments[0].End, sp.Segments[0].Op == LineOp
+	}
+	last := len(sp.Segments) - 1
+	if last > 0 {
+		return sp.Segments[last-1].End, sp.Segments[last].Op == LineOp
+	}
+	return sp.Start, sp.Segments[last].Op == LineOp
+}
+
+// canMoveEnd reports whether the end can move to p along its line: a line
+// may grow or shrink as long as it keeps its direction, a curve may only be
+// carried on by a line
+func (sp Subpath) canMoveEnd(atStart bool, p Point) bool {
+	end, out := sp.outward(atStart)
+	if far, straight := sp.endLine(atStart); straight {
+		return dot(sub(p, far), sub(end, far)) > 0
+	}
+	return dot(sub(p, end), out) >= 0
+}
+
+// moveEnd moves the start or end of the subpath to p, see canMoveEnd
+func (sp *Subpath) moveEnd(atStart bool, p Point) {
+	_, straight := sp.endLine(atStart)
+	switch {
+	case atStart && straight:
+		sp.Start = p
+	case atStart:
+		sp.Segments = append([]Segment{{Op: LineOp, End: sp.Start}}, sp.Segments...)
+		sp.Start = p
+	case straight:
+		sp.Segments[len(sp.Segments)-1].End = p
+	default:
+		sp.Segments = append(sp.Segments, Segment{Op: LineOp, End: p})
+	}
+}
+
+func (sp Subpath) edges() []edge {
+	edges := make([]edge, 0, len(sp.Segments))
+	pos := sp.Start
+	for _, segment := range sp.Segments {
+		// Arcs are offset as the cubics that approximate them
+		parts := []Segment{segment}
+		if segment.Op == ArcOp {
+			parts = arcToCubics(pos, segment)
+		}
+		for _, part := range parts {
+			edges = append(edges, edge{op: part.Op, p0: pos, ctrl: part.Ctrl, ctrl2: part.Ctrl2, p1: part.End})
+			pos = part.End
+		}
+	}
+	return edges
+}
+
+// signedArea uses the polygon through the segment end points, closed back to
+// the start, which is close enough to tell the winding direction. Positive
+// means clockwise on the sheet (Y down).
+func (sp Subpath) signedArea() float64 {
+	area := 0.0
+	points := sp.Flatten(0)
+	for i := 0; i < len(points); i++ {
+		a, b := points[i], points[(i+1)%len(points)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	return area / 2
+}
+
+// offsetEdge shifts a single edge to the right by side
+func offsetEdge(e edge, side float64) edge {
+	if e.op == LineOp {
+		n := scale(rightNormal(e.startTangent()), side)
+		return edge{op: LineOp, p0: add(e.p0, n), p1: add(e.p1, n)}
+	}
+
+	n0 := scale(rightNormal(e.startTangent()), side)
+	n1 := scale(rightNormal(e.endTangent()), side)
+	p0 := add(e.p0, n0)
+	p1 := add(e.p1, n1)
+	if e.op == CubicOp {
+		// Each half of the control polygon moves with its end
+		return edge{op: CubicOp, p0: p0, ctrl: add(e.ctrl, n0), ctrl2: add(e.ctrl2, n1), p1: p1}
+	}
+	ctrl, ok := intersectLines(p0, e.startTangent(), p1, e.endTangent())
+	if !ok {
+		ctrl = add(e.ctrl, n0)
+	}
+	return edge{op: QuadOp, p0: p0, ctrl: ctrl, p1: p1}
+}
+
+// joinEdges closes the gaps and overlaps left between shifted edges
+func joinEdges(edges []edge, closed bool, distance float64) []edge {
+	count := len(edges)
+	joins := count - 1
+	if closed {
+		joins = count
+	}
+
+	for i := 0; i < joins; i++ {
+		a := &edges[i]
+		b := &edges[(i+1)%count]
+		if a.p1.Dist(b.p0) < 1e-9 {
+			continue
+		}
+		corner, ok := intersectLines(a.p1, a.endTangent(), b.p0, b.startTangent())
+		if ok && corner.Dist(midpoint(a.p1, b.p0)) <= miterLimit*distance {
+			a.p1 = corner
+			b.p0 = corner
+			continue
+		}
+		// Too sharp to mitre: the gap is left for Offset to bevel with a line
+	}
+	return edges
+}
+
+func intersectLines(p Point, dp Point, q Point, dq Point) (Point, bool) {
+	denom := cross(dp, dq)
+	if math.Abs(denom) < 1e-9 {
+		return Point{}, false
+	}
+	t := cross(sub(q, p), dq) / denom
+	return add(p, scale(dp, t)), true
+}
+
+func add(a, b Point) Point           { return Point{a.X + b.X, a.Y + b.Y} }
+func sub(a, b Point) Point           { return Point{a.X - b.X, a.Y - b.Y} }
+func scale(a Point, s float64) Point { return Point{a.X * s, a.Y * s} }
+func dot(a, b Point) float64         { return a.X*b.X + a.Y*b.Y }
+func cross(a, b Point) float64       { return a.X*b.Y - a.Y*b.X }
+func midpoint(a, b Point) Point      { return Point{(a.X + b.X) / 2, (a.Y + b.Y) / 2} }
+
+// rightNormal points to the right of the direction of travel on the sheet
+func rightNormal(d Point) Point { return Point{-d.Y, d.X} }
+
+func unit(a Point) Point {
+	length := math.Hypot(a.X, a.Y)
+	if length == 0 {
+		return Point{}
+	}
+	return Point{a.X / length, a.Y / length}
+}
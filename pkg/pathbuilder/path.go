@@ -0,0 +1,645 @@
+package pathbuilder
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Point is a position on the sheet. Paths are parsed into floats so that
+// later stages (ordering, offsets, machine output) don't lose precision.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Dist returns the straight line distance between two points
+func (p Point) Dist(q Point) float64 {
+	return math.Hypot(q.X-p.X, q.Y-p.Y)
+}
+
+type SegmentOp int
+
+const (
+	LineOp SegmentOp = iota
+	QuadOp
+	CubicOp
+	ArcOp
+)
+
+// Segment is one drawing command of a subpath. The start point is the end
+// of the previous segment (or the subpath start).
+type Segment struct {
+	Op   SegmentOp
+	Ctrl Point
+	// Ctrl2 is the second control point of a cubic
+	Ctrl2 Point
+	End   Point
+
+	// Elliptical arcs use the SVG parameters: the radii, the rotation of
+	// the x radius in degrees and the two flags picking one of four arcs.
+	Radii    Point
+	Rotation float64
+	LargeArc bool
+	Sweep    bool
+}
+
+// Subpath is a single pen-down run beginning with a MoveTo
+type Subpath struct {
+	Start    Point
+	Segments []Segment
+	Closed   bool
+}
+
+// Path is a parsed SVG path made of one or more subpaths
+type Path struct {
+	Subpaths []Subpath
+	// FillRule decides which subpaths are holes in the others
+	FillRule FillRule
+}
+
+// End returns the last point of the subpath
+func (sp Subpath) End() Point {
+	if len(sp.Segments) == 0 {
+		return sp.Start
+	}
+	return sp.Segments[len(sp.Segments)-1].End
+}
+
+// IsClosed reports whether the subpath returns to its start, either through
+// an explicit Z or because the last segment ends on the start point.
+func (sp Subpath) IsClosed() bool {
+	return sp.Closed || (len(sp.Segments) > 1 && sp.End().Dist(sp.Start) < 1e-6)
+}
+
+// Reverse returns the subpath drawn in the opposite direction
+func (sp Subpath) Reverse() Subpath {
+	reversed := Subpath{Start: sp.End(), Closed: sp.Closed}
+	for i := len(sp.Segments) - 1; i >= 0; i-- {
+		end := sp.Start
+		if i > 0 {
+			end = sp.Segments[i-1].End
+		}
+		segment := sp.Segments[i]
+		segment.End = end
+		// A cubic runs through its controls the other way round, an arc
+		// turns the other way
+		switch segment.Op {
+		case CubicOp:
+			segment.Ctrl, segment.Ctrl2 = segment.Ctrl2, segment.Ctrl
+		case ArcOp:
+			segment.Sweep = !segment.Sweep
+		}
+		reversed.Segments = append(reversed.Segments, segment)
+	}
+	return reversed
+}
+
+// Vertices returns the start point of every segment, which are the places a
+// closed subpath can be re-entered from.
+func (sp Subpath) Vertices() []Point {
+	points := []Point{sp.Start}
+	for i := 0; i < len(sp.Segments)-1; i++ {
+		points = append(points, sp.Segments[i].End)
+	}
+	return points
+}
+
+// RotateTo returns a closed subpath that starts at the beginning of segment i.
+// An explicit closing segment is added first if the subpath relies on Z.
+func (sp Subpath) RotateTo(i int) Subpath {
+	if i <= 0 || i >= len(sp.Segments) {
+		return sp
+	}
+	segments := sp.Segments
+	if sp.End().Dist(sp.Start) >= 1e-6 {
+		segments = append(append([]Segment{}, segments...), Segment{Op: LineOp, End: sp.Start})
+	}
+	rotated := Subpath{Start: segments[i-1].End, Closed: sp.Closed}
+	rotated.Segments = append(rotated.Segments, segments[i:]...)
+	rotated.Segments = append(rotated.Segments, segments[:i]...)
+	return rotated
+}
+
+// Bounds returns the bounding box of the subpath's points and control
+// points. Arcs contribute the points of their flattened curve.
+func (sp Subpath) Bounds() (minX, minY, maxX, maxY float64) {
+	minX, minY = sp.Start.X, sp.Start.Y
+	maxX, maxY = minX, minY
+	grow := func(p Point) {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	pos := sp.Start
+	for _, segment := range sp.Segments {
+		switch segment.Op {
+		case QuadOp:
+			grow(segment.Ctrl)
+		case CubicOp:
+			grow(segment.Ctrl)
+			grow(segment.Ctrl2)
+		case ArcOp:
+			points, _ := flattenSegment(pos, segment, 0.01)
+			for _, p := range points {
+				grow(p)
+			}
+		}
+		grow(segment.End)
+		pos = segment.End
+	}
+	return minX, minY, maxX, maxY
+}
+
+// Translate returns the subpath moved by dx, dy
+func (sp Subpath) Translate(dx, dy float64) Subpath {
+	move := func(p Point) Point { return Point{p.X + dx, p.Y + dy} }
+	moved := Subpath{Start: move(sp.Start), Closed: sp.Closed}
+	for _, segment := range sp.Segments {
+		segment.Ctrl = move(segment.Ctrl)
+		segment.Ctrl2 = move(segment.Ctrl2)
+		segment.End = move(segment.End)
+		moved.Segments = append(moved.Segments, segment)
+	}
+	return moved
+}
+
+// String renders the subpath as SVG path data
+func (sp Subpath) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "M%s,%s", formatNumber(sp.Start.X), formatNumber(sp.Start.Y))
+	for _, segment := range sp.Segments {
+		sb.WriteString(segment.String())
+	}
+	if sp.Closed {
+		sb.WriteString("Z")
+	}
+	return sb.String()
+}
+
+// String renders the segment as a single absolute SVG command
+func (s Segment) String() string {
+	switch s.Op {
+	case QuadOp:
+		return fmt.Sprintf("Q%s,%s,%s,%s",
+			formatNumber(s.Ctrl.X), formatNumber(s.Ctrl.Y),
+			formatNumber(s.End.X), formatNumber(s.End.Y))
+	case CubicOp:
+		return fmt.Sprintf("C%s,%s,%s,%s,%s,%s",
+			formatNumber(s.Ctrl.X), formatNumber(s.Ctrl.Y),
+			formatNumber(s.Ctrl2.X), formatNumber(s.Ctrl2.Y),
+			formatNumber(s.End.X), formatNumber(s.End.Y))
+	case ArcOp:
+		return fmt.Sprintf("A%s,%s,%s,%s,%s,%s,%s",
+			formatNumber(s.Radii.X), formatNumber(s.Radii.Y),
+			formatNumber(s.Rotation), formatFlag(s.LargeArc), formatFlag(s.Sweep),
+			formatNumber(s.End.X), formatNumber(s.End.Y))
+	default:
+		return fmt.Sprintf("L%s,%s", formatNumber(s.End.X), formatNumber(s.End.Y))
+	}
+}
+
+// Rect returns a closed rectangle drawn clockwise on the sheet
+func Rect(x, y, width, height float64) Subpath {
+	return Subpath{
+		Start: Point{x, y},
+		Segments: []Segment{
+			{Op: LineOp, End: Point{x + width, y}},
+			{Op: LineOp, End: Point{x + width, y + height}},
+			{Op: LineOp, End: Point{x, y + height}},
+		},
+		Closed: true,
+	}
+}
+
+// RoundedRect returns a closed rectangle with quadratic corners of the given
+// radius, limited to half the shorter side
+func RoundedRect(x, y, width, height, radius float64) Subpath {
+	radius = math.Min(radius, math.Min(width, height)/2)
+	if radius <= 0 {
+		return Rect(x, y, width, height)
+	}
+	right, bottom := x+width, y+height
+	return Subpath{
+		Start: Point{x + radius, y},
+		Segments: []Segment{
+			{Op: LineOp, End: Point{right - radius, y}},
+			{Op: QuadOp, Ctrl: Point{right, y}, End: Point{right, y + radius}},
+			{Op: LineOp, End: Point{right, bottom - radius}},
+			{Op: QuadOp, Ctrl: Point{right, bottom}, End: Point{right - radius, bottom}},
+			{Op: LineOp, End: Point{x + radius, bottom}},
+			{Op: QuadOp, Ctrl: Point{x, bottom}, End: Point{x, bottom - radius}},
+			{Op: LineOp, End: Point{x, y + radius}},
+			{Op: QuadOp, Ctrl: Point{x, y}, End: Point{x + radius, y}},
+		},
+		Closed: true,
+	}
+}
+
+// Ellipse returns a closed ellipse made of eight quadratic segments, which
+// stays within a fraction of a percent of the true curve
+func Ellipse(cx, cy, rx, ry float64) Subpath {
+	at := func(angle, scale float64) Point {
+		return Point{cx + rx*scale*math.Cos(angle), cy + ry*scale*math.Sin(angle)}
+	}
+	const step = math.Pi / 4
+	sp := Subpath{Start: at(0, 1), Closed: true}
+	for i := 1; i <= 8; i++ {
+		sp.Segments = append(sp.Segments, Segment{
+			Op:   QuadOp,
+			Ctrl: at((float64(i)-0.5)*step, 1/math.Cos(step/2)),
+			End:  at(float64(i)*step, 1),
+		})
+	}
+	return sp
+}
+
+// String renders the whole path as SVG path data
+func (p Path) String() string {
+	var sb strings.Builder
+	for _, sp := range p.Subpaths {
+		sb.WriteString(sp.String())
+	}
+	return sb.String()
+}
+
+// Translate returns the path moved by dx, dy
+func (p Path) Translate(dx, dy float64) Path {
+	moved := Path{FillRule: p.FillRule}
+	for _, sp := range p.Subpaths {
+		moved.Subpaths = append(moved.Subpaths, sp.Translate(dx, dy))
+	}
+	return moved
+}
+
+// Bounds returns the bounding box of every subpath, see Subpath.Bounds
+func (p Path) Bounds() (minX, minY, maxX, maxY float64) {
+	for i, sp := range p.Subpaths {
+		x0, y0, x1, y1 := sp.Bounds()
+		if i == 0 {
+			minX, minY, maxX, maxY = x0, y0, x1, y1
+			continue
+		}
+		minX, minY = math.Min(minX, x0), math.Min(minY, y0)
+		maxX, maxY = math.Max(maxX, x1), math.Max(maxY, y1)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// Reverse returns the path drawn backwards: the subpaths in the opposite
+// order, each reversed
+func (p Path) Reverse() Path {
+	reversed := Path{Subpaths: make([]Subpath, len(p.Subpaths)), FillRule: p.FillRule}
+	for i, sp := range p.Subpaths {
+		reversed.Subpaths[len(p.Subpaths)-1-i] = sp.Reverse()
+	}
+	return reversed
+}
+
+// Segments iterates over every segment of the path with the point it is
+// drawn from. The line a Z draws back to the start is not included.
+func (p Path) Segments() iter.Seq2[Point, Segment] {
+	return func(yield func(Point, Segment) bool) {
+		for _, sp := range p.Subpaths {
+			pos := sp.Start
+			for _, segment := range sp.Segments {
+				if !yield(pos, segment) {
+					return
+				}
+				pos = segment.End
+			}
+		}
+	}
+}
+
+// formatNumber prints coordinates without trailing zeros so integer paths
+// round-trip unchanged.
+func formatNumber(v float64) string {
+	v = math.Round(v*1000) / 1000
+	if v == 0 {
+		v = 0 // avoid "-0"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatFlag(flag bool) string {
+	if flag {
+		return "1"
+	}
+	return "0"
+}
+
+// ParsePath reads SVG path data: the M, L, H, V, Q, C, A and Z commands
+// that the builder produces and the S and T shorthands that drawing
+// programs such as Inkscape write, absolute and relative.
+func ParsePath(d string) (Path, error) {
+	var path Path
+	var current *Subpath
+	var pos Point
+	tokens := tokenizePath(d)
+	cmd := byte(0)
+
+	number := func(i *int) (float64, error) {
+		if *i >= len(tokens) || isCommand(tokens[*i]) {
+			return 0, fmt.Errorf("command %q is missing a number", cmd)
+		}
+		v, err := strconv.ParseFloat(tokens[*i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q: %w", tokens[*i], err)
+		}
+		*i++
+		return v, nil
+	}
+
+	// Arc flags are single digits and may run into the next number, e.g.
+	// "a5 5 0 0110 10"
+	flag := func(i *int) (bool, error) {
+		if *i >= len(tokens) || isCommand(tokens[*i]) {
+			return false, fmt.Errorf("command %q is missing a flag", cmd)
+		}
+		token := tokens[*i]
+		if token[0] != '0' && token[0] != '1' {
+			return false, fmt.Errorf("invalid arc flag %q", token)
+		}
+		if len(token) > 1 {
+			tokens[*i] = token[1:]
+		} else {
+			*i++
+		}
+		return token[0] == '1', nil
+	}
+
+	// reflected is the control point the S and T shorthands start with: the
+	// previous curve's last control point mirrored through pos, or pos when
+	// the previous segment isn't a curve of the same kind
+	reflected := func(op SegmentOp) Point {
+		if current == nil || len(current.Segments) == 0 {
+			return pos
+		}
+		last := current.Segments[len(current.Segments)-1]
+		switch {
+		case op == QuadOp && last.Op == QuadOp:
+			return sub(scale(pos, 2), last.Ctrl)
+		case op == CubicOp && last.Op == CubicOp:
+			return sub(scale(pos, 2), last.Ctrl2)
+		}
+		return pos
+	}
+
+	for i := 0; i < len(tokens); {
+		if isCommand(tokens[i]) {
+			cmd = tokens[i][0]
+			i++
+		} else if cmd == 0 {
+			return Path{}, fmt.Errorf("path must start with a command, got %q", tokens[i])
+		}
+
+		relative := cmd >= 'a' && cmd <= 'z'
+		offset := Point{}
+		if relative {
+			offset = pos
+		}
+
+		switch cmd {
+		case 'M', 'm':
+			x, err := number(&i)
+			if err != nil {
+				return Path{}, err
+			}
+			y, err := number(&i)
+			if err != nil {
+				return Path{}, err
+			}
+			pos = Point{offset.X + x, offset.Y + y}
+			path.Subpaths = append(path.Subpaths, Subpath{Start: pos})
+			current = &path.Subpaths[len(path.Subpaths)-1]
+			// Further coordinate pairs after a MoveTo are implicit LineTos
+			if relative {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		case 'L', 'l', 'H', 'h', 'V', 'v':
+			if current == nil {
+				return Path{}, fmt.Errorf("command %q before MoveTo", cmd)
+			}
+			end := pos
+			switch cmd {
+			case 'L', 'l':
+				x, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				y, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				end = Point{offset.X + x, offset.Y + y}
+			case 'H', 'h':
+				x, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				end.X = offset.X + x
+			case 'V', 'v':
+				y, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				end.Y = offset.Y + y
+			}
+			current.Segments = append(current.Segments, Segment{Op: LineOp, End: end})
+			pos = end
+		case 'Q', 'q':
+			if current == nil {
+				return Path{}, fmt.Errorf("command %q before MoveTo", cmd)
+			}
+			var v [4]float64
+			for k := range v {
+				n, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				v[k] = n
+			}
+			segment := Segment{
+				Op:   QuadOp,
+				Ctrl: Point{offset.X + v[0], offset.Y + v[1]},
+				End:  Point{offset.X + v[2], offset.Y + v[3]},
+			}
+			current.Segments = append(current.Segments, segment)
+			pos = segment.End
+		case 'T', 't':
+			if current == nil {
+				return Path{}, fmt.Errorf("command %q before MoveTo", cmd)
+			}
+			var v [2]float64
+			for k := range v {
+				n, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				v[k] = n
+			}
+			segment := Segment{
+				Op:   QuadOp,
+				Ctrl: reflected(QuadOp),
+				End:  Point{offset.X + v[0], offset.Y + v[1]},
+			}
+			current.Segments = append(current.Segments, segment)
+			pos = segment.End
+		case 'C', 'c':
+			if current == nil {
+				return Path{}, fmt.Errorf("command %q before MoveTo", cmd)
+			}
+			var v [6]float64
+			for k := range v {
+				n, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				v[k] = n
+			}
+			segment := Segment{
+				Op:    CubicOp,
+				Ctrl:  Point{offset.X + v[0], offset.Y + v[1]},
+				Ctrl2: Point{offset.X + v[2], offset.Y + v[3]},
+				End:   Point{offset.X + v[4], offset.Y + v[5]},
+			}
+			current.Segments = append(current.Segments, segment)
+			pos = segment.End
+		case 'S', 's':
+			if current == nil {
+				return Path{}, fmt.Errorf("command %q before MoveTo", cmd)
+			}
+			var v [4]float64
+			for k := range v {
+				n, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				v[k] = n
+			}
+			segment := Segment{
+				Op:    CubicOp,
+				Ctrl:  reflected(CubicOp),
+				Ctrl2: Point{offset.X + v[0], offset.Y + v[1]},
+				End:   Point{offset.X + v[2], offset.Y + v[3]},
+			}
+			current.Segments = append(current.Segments, segment)
+			pos = segment.End
+		case 'A', 'a':
+			if current == nil {
+				return Path{}, fmt.Errorf("command %q before MoveTo", cmd)
+			}
+			var v [3]float64
+			for k := range v {
+				n, err := number(&i)
+				if err != nil {
+					return Path{}, err
+				}
+				v[k] = n
+			}
+			large, err := flag(&i)
+			if err != nil {
+				return Path{}, err
+			}
+			sweep, err := flag(&i)
+			if err != nil {
+				return Path{}, err
+			}
+			x, err := number(&i)
+			if err != nil {
+				return Path{}, err
+			}
+			y, err := number(&i)
+			if err != nil {
+				return Path{}, err
+			}
+			segment := Segment{
+				Op:       ArcOp,
+				Radii:    Point{math.Abs(v[0]), math.Abs(v[1])},
+				Rotation: v[2],
+				LargeArc: large,
+				Sweep:    sweep,
+				End:      Point{offset.X + x, offset.Y + y},
+			}
+			current.Segments = append(current.Segments, segment)
+			pos = segment.End
+		case 'Z', 'z':
+			if current == nil {
+				return Path{}, fmt.Errorf("command %q before MoveTo", cmd)
+			}
+			current.Closed = true
+			pos = current.Start
+			// A new subpath implicitly starts where the closed one began
+			path.Subpaths = append(path.Subpaths, Subpath{Start: pos})
+			current = &path.Subpaths[len(path.Subpaths)-1]
+			cmd = 0
+			if i < len(tokens) && !isCommand(tokens[i]) {
+				return Path{}, fmt.Errorf("unexpected number %q after Z", tokens[i])
+			}
+		default:
+			return Path{}, fmt.Errorf("unsupported path command %q", cmd)
+		}
+	}
+
+	// Drop the empty subpaths left behind by Z or a trailing MoveTo
+	subpaths := path.Subpaths[:0]
+	for _, sp := range path.Subpaths {
+		if len(sp.Segments) > 0 {
+			subpaths = append(subpaths, sp)
+		}
+	}
+	path.Subpaths = subpaths
+	return path, nil
+}
+
+func isCommand(token string) bool {
+	return len(token) == 1 && strings.ContainsRune("MmLlHhVvQqTtCcSsAaZz", rune(token[0]))
+}
+
+// tokenizePath splits path data into single letter commands and numbers
+func tokenizePath(d string) []string {
+	var tokens []string
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, d[start:end])
+			start = -1
+		}
+	}
+	for i := 0; i < len(d); i++ {
+		c := d[i]
+		switch {
+		case c == ' ' || c == ',' || c == '\t' || c == '\n' || c == '\r':
+			flush(i)
+		case (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && c != 'e' && c != 'E':
+			flush(i)
+			tokens = append(tokens, string(c))
+		case c == '-' || c == '+':
+			// A sign starts a new number unless it belongs to an exponent
+			if start >= 0 && d[i-1] != 'e' && d[i-1] != 'E' {
+				flush(i)
+			}
+			if start < 0 {
+				start = i
+			}
+		case c == '.':
+			// A second decimal point starts a new number, e.g. "0.5.5"
+			if start >= 0 && strings.Contains(d[start:i], ".") {
+				flush(i)
+			}
+			if start < 0 {
+				start = i
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	flush(len(d))
+	return tokens
+}
@@ -1,7 +1,6 @@
 package pathbuilder
 
 import (
-	"fmt"
 	"math"
 	"strings"
 )
@@ -18,32 +17,70 @@ type PathSegment struct {
 	EndY       int
 	CornerType CornerType
 	Radius     int
+
+	// Op is the kind of segment, a straight line unless set. Curves keep
+	// their control points and arcs their SVG parameters.
+	Op          SegmentOp
+	CtrlX       int
+	CtrlY       int
+	Ctrl2X      int
+	Ctrl2Y      int
+	ArcRadiusX  int
+	ArcRadiusY  int
+	ArcRotation int
+	LargeArc    bool
+	Sweep       bool
 }
 
 type AdvancedPathBuilder struct {
-	commands []string
+	// subpaths holds one entry per MoveTo, the last is being drawn
+	subpaths []builderSubpath
 	lastX    int
 	lastY    int
+	// hole marks the subpath started by the next MoveTo as a hole
+	hole     bool
+	fillRule FillRule
+	// frame maps the coordinates given to the builder onto the sheet
+	frame Frame
+}
+
+// builderSubpath is a MoveTo and the segments drawn from it. Corners are
+// rounded within a subpath, never across to the next.
+type builderSubpath struct {
+	startX   int
+	startY   int
 	segments []PathSegment
+	closed   bool
+	hole     bool
 }
 
 type CornerBuilder struct {
 	pathBuilder *AdvancedPathBuilder
 	endX        int
 	endY        int
+	// curve holds the curve's parameters, zero for a line
+	curve PathSegment
 }
 
 // NewAdvancedPathBuilder creates a new path builder instance
 func NewAdvancedPathBuilder() *AdvancedPathBuilder {
 	return &AdvancedPathBuilder{
-		commands: make([]string, 0),
-		segments: make([]PathSegment, 0),
+		subpaths: make([]builderSubpath, 0),
 	}
 }
 
-// MoveTo sets the starting point for the path
+// InFrame draws the following commands in a local frame. Absolute points
+// are placed by the frame and relative lines turn with it.
+func (apb *AdvancedPathBuilder) InFrame(f Frame) *AdvancedPathBuilder {
+	apb.frame = f
+	return apb
+}
+
+// MoveTo starts a new subpath at the given point
 func (apb *AdvancedPathBuilder) MoveTo(x, y int) *AdvancedPathBuilder {
-	apb.commands = append(apb.commands, fmt.Sprintf("M%d,%d", x, y))
+	x, y = apb.frame.sheetInt(x, y)
+	apb.subpaths = append(apb.subpaths, builderSubpath{startX: x, startY: y, hole: apb.hole})
+	apb.hole = false
 	apb.lastX = x
 	apb.lastY = y
 	return apb
@@ -51,6 +88,7 @@ func (apb *AdvancedPathBuilder) MoveTo(x, y int) *AdvancedPathBuilder {
 
 // LineTo draws a line to absolute coordinates
 func (apb *AdvancedPathBuilder) LineTo(x, y int) *CornerBuilder {
+	x, y = apb.frame.sheetInt(x, y)
 	return &CornerBuilder{
 		pathBuilder: apb,
 		endX:        x,
@@ -58,8 +96,10 @@ func (apb *AdvancedPathBuilder) LineTo(x, y int) *CornerBuilder {
 	}
 }
 
-// RelativeLine moves relative to current position
+// RelativeLine draws a line by an offset from the end of the previous
+// segment, or from the MoveTo when it is the first
 func (apb *AdvancedPathBuilder) RelativeLine(offsetX, offsetY int) *CornerBuilder {
+	offsetX, offsetY = apb.frame.vectorInt(offsetX, offsetY)
 	newX := apb.lastX + offsetX
 	newY := apb.lastY + offsetY
 	return &CornerBuilder{
@@ -79,35 +119,135 @@ func (apb *AdvancedPathBuilder) VerticalLine(offset int) *CornerBuilder {
 	return apb.RelativeLine(0, offset)
 }
 
-// CurrentPosition returns the current drawing position
+// QuadTo draws a quadratic curve to absolute coordinates
+func (apb *AdvancedPathBuilder) QuadTo(ctrlX, ctrlY, x, y int) *CornerBuilder {
+	ctrlX, ctrlY = apb.frame.sheetInt(ctrlX, ctrlY)
+	x, y = apb.frame.sheetInt(x, y)
+	return &CornerBuilder{
+		pathBuilder: apb,
+		endX:        x,
+		endY:        y,
+		curve:       PathSegment{Op: QuadOp, CtrlX: ctrlX, CtrlY: ctrlY},
+	}
+}
+
+// RelativeQuadTo draws a quadratic curve with the control point and end
+// given relative to the current position
+func (apb *AdvancedPathBuilder) RelativeQuadTo(ctrlX, ctrlY, offsetX, offsetY int) *CornerBuilder {
+	ctrlX, ctrlY = apb.frame.vectorInt(ctrlX, ctrlY)
+	offsetX, offsetY = apb.frame.vectorInt(offsetX, offsetY)
+	return &CornerBuilder{
+		pathBuilder: apb,
+		endX:        apb.lastX + offsetX,
+		endY:        apb.lastY + offsetY,
+		curve:       PathSegment{Op: QuadOp, CtrlX: apb.lastX + ctrlX, CtrlY: apb.lastY + ctrlY},
+	}
+}
+
+// CurveTo draws a cubic Bézier curve to absolute coordinates
+func (apb *AdvancedPathBuilder) CurveTo(ctrl1X, ctrl1Y, ctrl2X, ctrl2Y, x, y int) *CornerBuilder {
+	ctrl1X, ctrl1Y = apb.frame.sheetInt(ctrl1X, ctrl1Y)
+	ctrl2X, ctrl2Y = apb.frame.sheetInt(ctrl2X, ctrl2Y)
+	x, y = apb.frame.sheetInt(x, y)
+	return &CornerBuilder{
+		pathBuilder: apb,
+		endX:        x,
+		endY:        y,
+		curve:       PathSegment{Op: CubicOp, CtrlX: ctrl1X, CtrlY: ctrl1Y, Ctrl2X: ctrl2X, Ctrl2Y: ctrl2Y},
+	}
+}
+
+// RelativeCurveTo draws a cubic Bézier curve with the control points and
+// end given relative to the current position
+func (apb *AdvancedPathBuilder) RelativeCurveTo(ctrl1X, ctrl1Y, ctrl2X, ctrl2Y, offsetX, offsetY int) *CornerBuilder {
+	ctrl1X, ctrl1Y = apb.frame.vectorInt(ctrl1X, ctrl1Y)
+	ctrl2X, ctrl2Y = apb.frame.vectorInt(ctrl2X, ctrl2Y)
+	offsetX, offsetY = apb.frame.vectorInt(offsetX, offsetY)
+	return &CornerBuilder{
+		pathBuilder: apb,
+		endX:        apb.lastX + offsetX,
+		endY:        apb.lastY + offsetY,
+		curve: PathSegment{
+			Op:     CubicOp,
+			CtrlX:  apb.lastX + ctrl1X,
+			CtrlY:  apb.lastY + ctrl1Y,
+			Ctrl2X: apb.lastX + ctrl2X,
+			Ctrl2Y: apb.lastY + ctrl2Y,
+		},
+	}
+}
+
+// ArcTo draws an elliptical arc to absolute coordinates, with the radii,
+// x axis rotation in degrees and flags of the SVG A command
+func (apb *AdvancedPathBuilder) ArcTo(radiusX, radiusY, rotation int, largeArc, sweep bool, x, y int) *CornerBuilder {
+	x, y = apb.frame.sheetInt(x, y)
+	return &CornerBuilder{
+		pathBuilder: apb,
+		endX:        x,
+		endY:        y,
+		curve:       apb.arc(radiusX, radiusY, rotation, largeArc, sweep),
+	}
+}
+
+// RelativeArcTo draws an elliptical arc ending at an offset from the
+// current position
+func (apb *AdvancedPathBuilder) RelativeArcTo(radiusX, radiusY, rotation int, largeArc, sweep bool, offsetX, offsetY int) *CornerBuilder {
+	offsetX, offsetY = apb.frame.vectorInt(offsetX, offsetY)
+	return &CornerBuilder{
+		pathBuilder: apb,
+		endX:        apb.lastX + offsetX,
+		endY:        apb.lastY + offsetY,
+		curve:       apb.arc(radiusX, radiusY, rotation, largeArc, sweep),
+	}
+}
+
+// arc turns the ellipse with the frame. The frame never mirrors, so the
+// sweep direction is kept.
+func (apb *AdvancedPathBuilder) arc(radiusX, radiusY, rotation int, largeArc, sweep bool) PathSegment {
+	return PathSegment{
+		Op:          ArcOp,
+		ArcRadiusX:  radiusX,
+		ArcRadiusY:  radiusY,
+		ArcRotation: rotation + 90*apb.frame.Rotation,
+		LargeArc:    largeArc,
+		Sweep:       sweep,
+	}
+}
+
+// CurrentPosition returns the current drawing position in the builder's frame
 func (apb *AdvancedPathBuilder) CurrentPosition() (int, int) {
-	return apb.lastX, apb.lastY
+	p := apb.frame.Local(Point{float64(apb.lastX), float64(apb.lastY)})
+	return int(math.Round(p.X)), int(math.Round(p.Y))
 }
 
 // Square creates a sharp corner at this point
 func (cb *CornerBuilder) Square() *AdvancedPathBuilder {
-	cb.pathBuilder.segments = append(cb.pathBuilder.segments, PathSegment{
-		EndX:       cb.endX,
-		EndY:       cb.endY,
-		CornerType: SquareCorner,
-	})
+	segment := cb.curve
+	segment.EndX = cb.endX
+	segment.EndY = cb.endY
+	segment.CornerType = SquareCorner
+	cb.pathBuilder.add(segment)
+	cb.pathBuilder.lastX = cb.endX
+	cb.pathBuilder.lastY = cb.endY
 	return cb.pathBuilder
 }
 
 // Rounded creates a rounded corner with the specified radius
 func (cb *CornerBuilder) Rounded(radius int) *AdvancedPathBuilder {
-	cb.pathBuilder.segments = append(cb.pathBuilder.segments, PathSegment{
-		EndX:       cb.endX,
-		EndY:       cb.endY,
-		CornerType: RoundedCorner,
-		Radius:     radius,
-	})
+	segment := cb.curve
+	segment.EndX = cb.endX
+	segment.EndY = cb.endY
+	segment.CornerType = RoundedCorner
+	segment.Radius = radius
+	cb.pathBuilder.add(segment)
+	cb.pathBuilder.lastX = cb.endX
+	cb.pathBuilder.lastY = cb.endY
 	return cb.pathBuilder
 }
 
 // generateRoundedCorner creates a smooth curve at a corner
-func (apb *AdvancedPathBuilder) generateRoundedCorner(prevX, prevY, cornerX, cornerY, nextX, nextY, radius int) []string {
-	var commands []string
+func (apb *AdvancedPathBuilder) generateRoundedCorner(prevX, prevY, cornerX, cornerY, nextX, nextY, radius int) []Segment {
+	point := func(x, y int) Point { return Point{float64(x), float64(y)} }
 
 	// Calculate vectors from corner point
 	incomingX := float64(prevX - cornerX)
@@ -121,8 +261,7 @@ func (apb *AdvancedPathBuilder) generateRoundedCorner(prevX, prevY, cornerX, cor
 
 	// Handle edge cases
 	if incomingLen == 0 || outgoingLen == 0 {
-		commands = append(commands, fmt.Sprintf("L%d,%d", cornerX, cornerY))
-		return commands
+		return []Segment{{Op: LineOp, End: point(cornerX, cornerY)}}
 	}
 
 	incomingUnitX := incomingX / incomingLen
@@ -144,70 +283,337 @@ func (apb *AdvancedPathBuilder) generateRoundedCorner(prevX, prevY, cornerX, cor
 	curveEndX := cornerX + int(outgoingUnitX*radiusF)
 	curveEndY := cornerY + int(outgoingUnitY*radiusF)
 
-	// Line to curve start
-	commands = append(commands, fmt.Sprintf("L%d,%d", curveStartX, curveStartY))
-	// Quadratic curve through the corner
-	commands = append(commands, fmt.Sprintf("Q%d,%d,%d,%d", cornerX, cornerY, curveEndX, curveEndY))
+	return []Segment{
+		// Line to curve start
+		{Op: LineOp, End: point(curveStartX, curveStartY)},
+		// Quadratic curve through the corner
+		{Op: QuadOp, Ctrl: point(cornerX, cornerY), End: point(curveEndX, curveEndY)},
+	}
+}
+
+// Build generates the path, one subpath per MoveTo. Holes are turned to
+// wind against the outline around them, so they stay open under either
+// fill rule.
+func (apb *AdvancedPathBuilder) Build() Path {
+	path := Path{FillRule: apb.fillRule}
+	for _, sub := range apb.subpaths {
+		path.Subpaths = append(path.Subpaths, apb.buildSubpath(sub))
+	}
+	for i, sub := range apb.subpaths {
+		if !sub.hole {
+			continue
+		}
+		outline, ok := apb.outlineAround(path, i)
+		if ok && (path.Subpaths[i].signedArea() > 0) == (outline.signedArea() > 0) {
+			path.Subpaths[i] = path.Subpaths[i].Reverse()
+		}
+	}
+	return path
+}
 
-	return commands
+// outlineAround finds the smallest subpath that isn't a hole and contains
+// the hole at index i, falling back to the first that isn't a hole
+func (apb *AdvancedPathBuilder) outlineAround(path Path, i int) (Subpath, bool) {
+	var outline Subpath
+	found, area := false, math.Inf(1)
+	for j, sub := range apb.subpaths {
+		if sub.hole {
+			continue
+		}
+		candidate := path.Subpaths[j]
+		enclosing := Path{Subpaths: []Subpath{candidate}}.Contains(path.Subpaths[i].Start)
+		if size := math.Abs(candidate.signedArea()); (enclosing && size < area) || !found {
+			outline, found = candidate, true
+			if enclosing {
+				area = size
+			}
+		}
+	}
+	return outline, found
 }
 
-// Build generates the final SVG path string
-func (apb *AdvancedPathBuilder) Build() string {
-	if len(apb.segments) == 0 {
-		return strings.Join(apb.commands, "")
+// buildSubpath replays the segments of one subpath. Rounded corners between
+// two lines are cut across with a quadratic; where a curve meets the corner
+// the curve is trimmed back instead and the fillet joins it along its
+// tangent.
+func (apb *AdvancedPathBuilder) buildSubpath(sub builderSubpath) Subpath {
+	sp := Subpath{Start: Point{float64(sub.startX), float64(sub.startY)}, Closed: sub.closed}
+	if len(sub.segments) == 0 {
+		return sp
 	}
 
-	currentX := apb.lastX
-	currentY := apb.lastY
+	corners := sub.curveCorners()
+	last := len(sub.segments) - 1
 
-	for i, segment := range apb.segments {
-		if i == len(apb.segments)-1 || segment.CornerType == SquareCorner {
-			apb.commands = append(apb.commands, fmt.Sprintf("L%d,%d", segment.EndX, segment.EndY))
-		} else if segment.CornerType == RoundedCorner && i < len(apb.segments)-1 {
-			nextSegment := apb.segments[i+1]
-			curves := apb.generateRoundedCorner(
-				currentX, currentY,
-				segment.EndX, segment.EndY,
-				nextSegment.EndX, nextSegment.EndY,
-				segment.Radius,
-			)
-			apb.commands = append(apb.commands, curves...)
+	// Segments are replayed from the MoveTo position
+	currentX := sub.startX
+	currentY := sub.startY
+	// from is how far along the segment the previous corner left off
+	from := 0.0
+	if closing, ok := corners[last]; ok {
+		from = closing.join
+	}
+
+	for i, segment := range sub.segments {
+		corner, curved := corners[i]
+		if segment.Op == LineOp && !curved {
+			if i == last || segment.CornerType == SquareCorner || sub.segments[i+1].Op != LineOp {
+				sp.Segments = append(sp.Segments, Segment{
+					Op:  LineOp,
+					End: Point{float64(segment.EndX), float64(segment.EndY)},
+				})
+			} else {
+				nextSegment := sub.segments[i+1]
+				curves := apb.generateRoundedCorner(
+					currentX, currentY,
+					segment.EndX, segment.EndY,
+					nextSegment.EndX, nextSegment.EndY,
+					segment.Radius,
+				)
+				sp.Segments = append(sp.Segments, curves...)
+			}
+		} else {
+			to := 1.0
+			if curved {
+				to = corner.leave
+			}
+			p0, part := sub.segmentAt(i)
+			sp.Segments = append(sp.Segments, trimSegment(p0, part, from, to))
+			if curved {
+				sp.Segments = append(sp.Segments, corner.fillet)
+			}
+		}
+
+		from = 0
+		if curved {
+			from = corner.join
 		}
 		currentX = segment.EndX
 		currentY = segment.EndY
 	}
 
-	return strings.Join(apb.commands, "")
+	if sub.closed {
+		if closing, ok := corners[last]; ok {
+			// The fillet drawn last ends where the path now starts
+			sp.Start = closing.fillet.End
+		} else if sub.segments[0].Op == LineOp && sub.segments[last].Op == LineOp {
+			apb.closeCorner(sub, &sp)
+		}
+	}
+
+	return sp
+}
+
+// curveCorner is a rounded corner with a curve on at least one side: the
+// parameter where the fillet leaves the segment coming in, the fillet, and
+// the parameter where it joins the segment going out
+type curveCorner struct {
+	leave  float64
+	fillet Segment
+	join   float64
+}
+
+// curveCorners finds the rounded corners next to a curve, keyed by the
+// segment coming into the corner. The start corner of a closed path is
+// keyed by the last segment.
+func (sub builderSubpath) curveCorners() map[int]curveCorner {
+	corners := make(map[int]curveCorner)
+	last := len(sub.segments) - 1
+	for i, segment := range sub.segments {
+		next := i + 1
+		if i == last {
+			if !sub.closed || last < 1 || segment.EndX != sub.startX || segment.EndY != sub.startY {
+				continue
+			}
+			next = 0
+		}
+		if segment.CornerType != RoundedCorner || segment.Radius <= 0 {
+			continue
+		}
+		if segment.Op == LineOp && sub.segments[next].Op == LineOp {
+			continue
+		}
+		inStart, in := sub.segmentAt(i)
+		_, out := sub.segmentAt(next)
+		corners[i] = roundCurveCorner(inStart, in, out, float64(segment.Radius))
+	}
+	return corners
+}
+
+// roundCurveCorner fits a fillet of the given radius, measured in a
+// straight line from the corner, between a segment drawn from p0 and the
+// segment that follows it. The radius is kept to half of either segment.
+func roundCurveCorner(p0 Point, in, out Segment, radius float64) curveCorner {
+	corner := in.End
+	radius = math.Min(radius, math.Min(p0.Dist(corner), corner.Dist(out.End))/2)
+
+	leave := trimParam(p0, in, 1, radius)
+	join := trimParam(corner, out, 0, radius)
+	a, b := in.PointAt(p0, leave), out.PointAt(corner, join)
+	tangentA, tangentB := in.TangentAt(p0, leave), out.TangentAt(corner, join)
+
+	// The control point sits where the two tangents cross, ahead of the
+	// first and behind the second; otherwise the corner itself is used
+	ctrl, ok := intersectLines(a, tangentA, b, tangentB)
+	if !ok || dot(sub(ctrl, a), tangentA) <= 0 || dot(sub(b, ctrl), tangentB) <= 0 {
+		ctrl = corner
+	}
+	return curveCorner{leave: leave, fillet: Segment{Op: QuadOp, Ctrl: ctrl, End: b}, join: join}
+}
+
+// trimParam returns the parameter on the segment drawn from p0 that lies
+// distance away from the point at parameter from (0 or 1), searching
+// towards the other end
+func trimParam(p0 Point, s Segment, from, distance float64) float64 {
+	anchor := s.PointAt(p0, from)
+	near, far := from, 1-from
+	for i := 0; i < 50; i++ {
+		mid := (near + far) / 2
+		if s.PointAt(p0, mid).Dist(anchor) < distance {
+			near = mid
+		} else {
+			far = mid
+		}
+	}
+	return (near + far) / 2
+}
+
+// trimSegment returns the part of the segment drawn from p0 between the
+// parameters from and to
+func trimSegment(p0 Point, s Segment, from, to float64) Segment {
+	if from > 0 {
+		// The tail is drawn from the split point
+		var first Segment
+		first, s = s.Split(p0, from)
+		p0 = first.End
+		to = (to - from) / (1 - from)
+	}
+	if to < 1 {
+		s, _ = s.Split(p0, to)
+	}
+	return s
+}
+
+// segmentAt returns segment i with the point it is drawn from
+func (sub builderSubpath) segmentAt(i int) (Point, Segment) {
+	start := Point{float64(sub.startX), float64(sub.startY)}
+	if i > 0 {
+		prev := sub.segments[i-1]
+		start = Point{float64(prev.EndX), float64(prev.EndY)}
+	}
+	s := sub.segments[i]
+	point := func(x, y int) Point { return Point{float64(x), float64(y)} }
+	return start, Segment{
+		Op:       s.Op,
+		Ctrl:     point(s.CtrlX, s.CtrlY),
+		Ctrl2:    point(s.Ctrl2X, s.Ctrl2Y),
+		End:      point(s.EndX, s.EndY),
+		Radii:    point(s.ArcRadiusX, s.ArcRadiusY),
+		Rotation: float64(s.ArcRotation),
+		LargeArc: s.LargeArc,
+		Sweep:    s.Sweep,
+	}
+}
+
+// closeCorner rounds the corner at the start of a closed path when the
+// segment returning to it asks for one. The path then starts where the
+// curve leaves the corner and the curve is drawn last.
+func (apb *AdvancedPathBuilder) closeCorner(sub builderSubpath, sp *Subpath) {
+	count := len(sub.segments)
+	last := sub.segments[count-1]
+	if count < 2 || last.CornerType != RoundedCorner || last.EndX != sub.startX || last.EndY != sub.startY {
+		return
+	}
+	prev := sub.segments[count-2]
+	next := sub.segments[0]
+	curves := apb.generateRoundedCorner(
+		prev.EndX, prev.EndY,
+		sub.startX, sub.startY,
+		next.EndX, next.EndY,
+		last.Radius,
+	)
+	if len(curves) < 2 {
+		return
+	}
+	// The last straight line already went to the corner, stop short instead
+	sp.Segments[len(sp.Segments)-1] = curves[0]
+	sp.Segments = append(sp.Segments, curves[1])
+	sp.Start = curves[1].End
 }
 
 // Clear resets the builder to start a new path
 func (apb *AdvancedPathBuilder) Clear() *AdvancedPathBuilder {
-	apb.commands = make([]string, 0)
-	apb.segments = make([]PathSegment, 0)
+	apb.subpaths = make([]builderSubpath, 0)
 	apb.lastX = 0
 	apb.lastY = 0
+	apb.hole = false
+	apb.fillRule = NonZero
+	apb.frame = Frame{}
 	return apb
 }
 
 // Clone creates a copy of the current builder state
 func (apb *AdvancedPathBuilder) Clone() *AdvancedPathBuilder {
 	newBuilder := NewAdvancedPathBuilder()
-	newBuilder.commands = make([]string, len(apb.commands))
-	copy(newBuilder.commands, apb.commands)
-	newBuilder.segments = make([]PathSegment, len(apb.segments))
-	copy(newBuilder.segments, apb.segments)
+	for _, sub := range apb.subpaths {
+		sub.segments = append([]PathSegment{}, sub.segments...)
+		newBuilder.subpaths = append(newBuilder.subpaths, sub)
+	}
 	newBuilder.lastX = apb.lastX
 	newBuilder.lastY = apb.lastY
+	newBuilder.hole = apb.hole
+	newBuilder.fillRule = apb.fillRule
+	newBuilder.frame = apb.frame
 	return newBuilder
 }
 
-// ClosePath adds a Z command to close the path
+// ClosePath adds a Z command to close the current subpath. A rounded
+// corner on the segment returning to the start rounds the start corner
+// too. Drawing carries on from the start point in a new subpath.
 func (apb *AdvancedPathBuilder) ClosePath() *AdvancedPathBuilder {
-	apb.commands = append(apb.commands, "Z")
+	sub := apb.current()
+	sub.closed = true
+	apb.lastX = sub.startX
+	apb.lastY = sub.startY
 	return apb
 }
 
+// Hole marks the subpath started by the next MoveTo as a cutout of the
+// outline it lies in
+func (apb *AdvancedPathBuilder) Hole() *AdvancedPathBuilder {
+	apb.hole = true
+	return apb
+}
+
+// SetFillRule sets the rule the built path is filled with
+func (apb *AdvancedPathBuilder) SetFillRule(rule FillRule) *AdvancedPathBuilder {
+	apb.fillRule = rule
+	return apb
+}
+
+// current returns the subpath being drawn. Drawing before any MoveTo
+// starts from the origin.
+func (apb *AdvancedPathBuilder) current() *builderSubpath {
+	if len(apb.subpaths) == 0 {
+		apb.subpaths = append(apb.subpaths, builderSubpath{hole: apb.hole})
+		apb.hole = false
+	}
+	return &apb.subpaths[len(apb.subpaths)-1]
+}
+
+// add appends a segment to the subpath being drawn. A segment drawn after
+// ClosePath starts a new subpath at the start point, as it does after a Z
+// in SVG.
+func (apb *AdvancedPathBuilder) add(segment PathSegment) {
+	sub := apb.current()
+	if sub.closed {
+		apb.subpaths = append(apb.subpaths, builderSubpath{startX: sub.startX, startY: sub.startY, hole: apb.hole})
+		apb.hole = false
+		sub = &apb.subpaths[len(apb.subpaths)-1]
+	}
+	sub.segments = append(sub.segments, segment)
+}
+
 // Utility functions for common shapes
 
 // CreateRectangle creates a rectangle with optional rounded corners
@@ -219,7 +625,8 @@ func CreateRectangle(x, y, width, height, radius int) string {
 			VerticalLine(height).Square().
 			HorizontalLine(-width).Square().
 			VerticalLine(-height).Square().
-			Build()
+			Build().
+			String()
 	}
 
 	return NewAdvancedPathBuilder().
@@ -228,7 +635,8 @@ func CreateRectangle(x, y, width, height, radius int) string {
 		VerticalLine(height).Rounded(radius).
 		HorizontalLine(-width).Rounded(radius).
 		VerticalLine(-height).Rounded(radius).
-		Build()
+		Build().
+		String()
 }
 
 // Validation functions
@@ -242,9 +650,13 @@ func ValidatePath(path string) bool {
 	return true
 }
 
-// GetPathBounds calculates the bounding box of a path
+// GetPathBounds returns the bounds of path data as Path.Bounds does, rounded
+// out to whole mm. Data that doesn't parse has empty bounds.
 func GetPathBounds(path string) (minX, minY, maxX, maxY int) {
-	// This would parse the path and calculate bounds
-	// Simplified implementation for now
-	return 0, 0, 100, 100
+	parsed, err := ParsePath(path)
+	if err != nil || len(parsed.Subpaths) == 0 {
+		return 0, 0, 0, 0
+	}
+	fMinX, fMinY, fMaxX, fMaxY := parsed.Bounds()
+	return int(math.Floor(fMinX)), int(math.Floor(fMinY)), int(math.Ceil(fMaxX)), int(math.Ceil(fMaxY))
 }
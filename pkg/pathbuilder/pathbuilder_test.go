@@ -0,0 +1,75 @@
+package pathbuilder
+
+import "testing"
+
+func TestTrimSegmentBothEnds(t *testing.T) {
+	p0 := Point{0, 0}
+	segments := map[string]Segment{
+		"quad":  {Op: QuadOp, Ctrl: Point{50, 80}, End: Point{100, 0}},
+		"cubic": {Op: CubicOp, Ctrl: Point{0, 100}, Ctrl2: Point{100, -100}, End: Point{100, 0}},
+		"arc":   {Op: ArcOp, Radii: Point{60, 30}, Rotation: 20, Sweep: true, End: Point{100, 0}},
+	}
+	const from, to = 0.2, 0.7
+	for name, s := range segments {
+		trimmed := trimSegment(p0, s, from, to)
+		start := s.PointAt(p0, from)
+		for _, u := range []float64{0, 0.25, 0.5, 0.75, 1} {
+			got := trimmed.PointAt(start, u)
+			want := s.PointAt(p0, from+u*(to-from))
+			if d := got.Dist(want); d > 1e-9 {
+				t.Errorf("%s at %.2f: got %v, want %v (%.3g off)", name, u, got, want, d)
+			}
+		}
+	}
+}
+
+func TestDrawingAfterClosePath(t *testing.T) {
+	got := NewAdvancedPathBuilder().
+		MoveTo(0, 0).
+		HorizontalLine(10).Square().
+		VerticalLine(10).Square().
+		ClosePath().
+		HorizontalLine(-10).Square().
+		Build().String()
+	if want := "M0,0L10,0L10,10ZM0,0L-10,0"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRelativeLinesChain(t *testing.T) {
+	got := NewAdvancedPathBuilder().
+		MoveTo(10, 20).
+		HorizontalLine(30).Square().
+		RelativeLine(5, 5).Square().
+		VerticalLine(10).Square().
+		MoveTo(0, 0).
+		RelativeLine(-5, 5).Square().
+		Build().String()
+	// Each offset runs on from where the last line ended, and a MoveTo
+	// starts measuring again
+	if want := "M10,20L40,20L45,25L45,35M0,0L-5,5"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestGetPathBounds(t *testing.T) {
+	tests := []struct {
+		path                   string
+		minX, minY, maxX, maxY int
+	}{
+		{"M10,20L40,20L40,35Z", 10, 20, 40, 35},
+		// Every subpath counts, and fractions round outwards
+		{"M0.5,2.5L3,3M-4.2,1L1,9.1", -5, 1, 3, 10},
+		// Curves are bounded by their control points, see Subpath.Bounds
+		{"M0,0Q50,100 100,0", 0, 0, 100, 100},
+		{"", 0, 0, 0, 0},
+		{"not a path", 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		minX, minY, maxX, maxY := GetPathBounds(tt.path)
+		if minX != tt.minX || minY != tt.minY || maxX != tt.maxX || maxY != tt.maxY {
+			t.Errorf("%q: got %d,%d to %d,%d, want %d,%d to %d,%d", tt.path,
+				minX, minY, maxX, maxY, tt.minX, tt.minY, tt.maxX, tt.maxY)
+		}
+	}
+}
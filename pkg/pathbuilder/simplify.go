@@ -0,0 +1,122 @@
+package pathbuilder
+
+import "math"
+
+// DropEmpty removes segments that draw nothing: lines, arcs and curves
+// whose points all lie within tolerance of where they start
+func (sp Subpath) DropEmpty(tolerance float64) Subpath {
+	result := Subpath{Start: sp.Start, Closed: sp.Closed}
+	pos := sp.Start
+	for _, segment := range sp.Segments {
+		empty := segment.End.Dist(pos) <= tolerance
+		switch segment.Op {
+		case QuadOp:
+			empty = empty && segment.Ctrl.Dist(pos) <= tolerance
+		case CubicOp:
+			empty = empty && segment.Ctrl.Dist(pos) <= tolerance && segment.Ctrl2.Dist(pos) <= tolerance
+		}
+		if empty {
+			continue
+		}
+		result.Segments = append(result.Segments, segment)
+		pos = segment.End
+	}
+	return result
+}
+
+// MergeLines joins straight segments that carry on in the same direction
+// into one line. Lines that double back are left alone.
+func (sp Subpath) MergeLines() Subpath {
+	result := Subpath{Start: sp.Start, Closed: sp.Closed}
+	before, pos := sp.Start, sp.Start
+	for _, segment := range sp.Segments {
+		last := len(result.Segments) - 1
+		if segment.Op == LineOp && last >= 0 && result.Segments[last].Op == LineOp {
+			in, out := sub(pos, before), sub(segment.End, pos)
+			if math.Abs(cross(unit(in), unit(out))) < 1e-9 && dot(in, out) > 0 {
+				result.Segments[last].End = segment.End
+				pos = segment.End
+				continue
+			}
+		}
+		result.Segments = append(result.Segments, segment)
+		before, pos = pos, segment.End
+	}
+	return result
+}
+
+// DropEmpty removes empty segments from every subpath, see
+// Subpath.DropEmpty. Subpaths left with nothing to draw are removed.
+func (p Path) DropEmpty(tolerance float64) Path {
+	result := Path{FillRule: p.FillRule}
+	for _, sp := range p.Subpaths {
+		if cleaned := sp.DropEmpty(tolerance); len(cleaned.Segments) > 0 {
+			result.Subpaths = append(result.Subpaths, cleaned)
+		}
+	}
+	return result
+}
+
+// MergeLines joins straight runs in every subpath, see Subpath.MergeLines
+func (p Path) MergeLines() Path {
+	result := Path{Subpaths: make([]Subpath, len(p.Subpaths)), FillRule: p.FillRule}
+	for i, sp := range p.Subpaths {
+		result.Subpaths[i] = sp.MergeLines()
+	}
+	return result
+}
+
+// JoinEnds chains open subpaths that meet end to end, within tolerance, into
+// one subpath, turning a subpath round where it meets the chain backwards.
+// A chain that comes back to where it started is closed. Subpaths meeting
+// at a point where more than two ends touch are chained in drawing order.
+func (p Path) JoinEnds(tolerance float64) Path {
+	result := Path{FillRule: p.FillRule}
+	used := make([]bool, len(p.Subpaths))
+	for i, sp := range p.Subpaths {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		if sp.IsClosed() || len(sp.Segments) == 0 {
+			result.Subpaths = append(result.Subpaths, sp)
+			continue
+		}
+		chain := sp
+		for grown := true; grown && !chain.IsClosed(); {
+			grown = false
+			for j, next := range p.Subpaths {
+				if used[j] || next.IsClosed() || len(next.Segments) == 0 {
+					continue
+				}
+				switch {
+				case next.Start.Dist(chain.End()) <= tolerance:
+				case next.End().Dist(chain.End()) <= tolerance:
+					next = next.Reverse()
+				case next.End().Dist(chain.Start) <= tolerance:
+					chain, next = next, chain
+				case next.Start.Dist(chain.Start) <= tolerance:
+					chain, next = next.Reverse(), chain
+				default:
+					continue
+				}
+				chain = chain.append(next)
+				used[j], grown = true, true
+			}
+		}
+		if chain.End().Dist(chain.Start) <= tolerance {
+			chain.Segments[len(chain.Segments)-1].End = chain.Start
+			chain.Closed = true
+		}
+		result.Subpaths = append(result.Subpaths, chain)
+	}
+	return result
+}
+
+// append carries the subpath on with the segments of next, which starts
+// where it ends
+func (sp Subpath) append(next Subpath) Subpath {
+	joined := Subpath{Start: sp.Start}
+	joined.Segments = append(append(joined.Segments, sp.Segments...), next.Segments...)
+	return joined
+}
@@ -0,0 +1,63 @@
+package pathbuilder
+
+import "testing"
+
+func TestDropEmpty(t *testing.T) {
+	sp := Subpath{Start: Point{0, 0}, Segments: []Segment{
+		{Op: LineOp, End: Point{0, 0}},
+		{Op: LineOp, End: Point{10, 0}},
+		{Op: LineOp, End: Point{10, 0.001}},
+		{Op: QuadOp, Ctrl: Point{10, 0}, End: Point{10, 0}},
+		{Op: QuadOp, Ctrl: Point{15, 5}, End: Point{10, 0}},
+		{Op: LineOp, End: Point{10, 10}},
+	}}
+	got := sp.DropEmpty(0.01).String()
+	if want := "M0,0L10,0Q15,5,10,0L10,10"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	path := Path{Subpaths: []Subpath{{Start: Point{5, 5}, Segments: []Segment{{Op: LineOp, End: Point{5, 5}}}}, sp}}
+	if got := len(path.DropEmpty(0.01).Subpaths); got != 1 {
+		t.Errorf("%d subpaths left, want the empty one removed", got)
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	sp := Subpath{Start: Point{0, 0}, Segments: []Segment{
+		{Op: LineOp, End: Point{5, 0}},
+		{Op: LineOp, End: Point{10, 0}},
+		// Turns a corner
+		{Op: LineOp, End: Point{10, 5}},
+		{Op: LineOp, End: Point{10, 10}},
+		// Doubles back along itself
+		{Op: LineOp, End: Point{10, 2}},
+		// A curve between two lines in line with each other
+		{Op: QuadOp, Ctrl: Point{12, 1}, End: Point{10, 0}},
+		{Op: LineOp, End: Point{10, -5}},
+	}}
+	got := sp.MergeLines().String()
+	if want := "M0,0L10,0L10,10L10,2Q12,1,10,0L10,-5"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestJoinEnds(t *testing.T) {
+	tests := []struct {
+		name, path, want string
+	}{
+		{"in order", "M0,0L10,0M10,0L10,10", "M0,0L10,0L10,10"},
+		{"drawn backwards", "M0,0L10,0M10,10L10,0", "M0,0L10,0L10,10"},
+		{"before the chain", "M10,0L10,10M0,0L10,0", "M0,0L10,0L10,10"},
+		{"round to the start", "M0,0L10,0M10,0L10,10M0,0L10,10", "M0,0L10,0L10,10L0,0Z"},
+		{"apart", "M0,0L10,0M10,1L10,10", "M0,0L10,0M10,1L10,10"},
+		{"closed shapes stay apart", "M0,0L10,0L10,10ZM10,0L20,0", "M0,0L10,0L10,10ZM10,0L20,0"},
+	}
+	for _, tt := range tests {
+		path, err := ParsePath(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := path.JoinEnds(0.01).String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
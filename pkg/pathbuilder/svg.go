@@ -0,0 +1,361 @@
+package pathbuilder
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// svgUnits converts CSS length units to mm
+var svgUnits = map[string]float64{
+	"mm": 1,
+	"cm": 10,
+	"in": 25.4,
+	"pt": 25.4 / 72,
+	"pc": 25.4 / 6,
+	"px": 25.4 / 96,
+	"":   25.4 / 96,
+}
+
+// svgHidden are elements whose contents are never drawn where they stand
+var svgHidden = map[string]bool{
+	"defs": true, "clipPath": true, "mask": true, "marker": true,
+	"pattern": true, "symbol": true, "metadata": true, "title": true, "desc": true,
+}
+
+// svgState is what an element passes on to the elements inside it
+type svgState struct {
+	transform Transform
+	fillRule  FillRule
+}
+
+// ParseSVG reads the shapes of an SVG document, such as a flap drawn in
+// Inkscape, into one path in mm. Paths, rectangles, circles, ellipses,
+// lines, polylines and polygons are read with the transforms of their
+// groups applied, and the document's width, height and viewBox turn user
+// units into mm. Text, images and anything inside defs are ignored.
+//
+// Each element is filled by its own fill rule, so the closed shapes of an
+// element are resolved into their outline as it is read: drawn clockwise
+// with holes anticlockwise, as Union returns them. The result reads the
+// same under the nonzero rule and separate shapes that overlap never cancel
+// out. Open lines are kept as they are drawn.
+func ParseSVG(r io.Reader) (Path, error) {
+	decoder := xml.NewDecoder(r)
+	var result Path
+	// Each open element pushes the state that applies inside it
+	stack := []svgState{{transform: Identity()}}
+	hidden := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Path{}, fmt.Errorf("reading SVG: %w", err)
+		}
+		switch el := token.(type) {
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if hidden > 0 {
+				hidden--
+			}
+		case xml.StartElement:
+			attrs := svgAttributes(el)
+			parent := stack[len(stack)-1]
+			if hidden > 0 || svgHidden[el.Name.Local] || attrs["display"] == "none" {
+				stack = append(stack, parent)
+				hidden++
+				continue
+			}
+
+			t, err := parseTransform(attrs["transform"])
+			if err != nil {
+				return Path{}, fmt.Errorf("%s: %w", el.Name.Local, err)
+			}
+			if el.Name.Local == "svg" && len(stack) == 1 {
+				units, err := svgViewport(attrs)
+				if err != nil {
+					return Path{}, err
+				}
+				t = t.Then(units)
+			}
+			state := svgState{transform: t.Then(parent.transform), fillRule: parent.fillRule}
+			// The fill rule is inherited unless the element sets its own
+			switch attrs["fill-rule"] {
+			case "evenodd":
+				state.fillRule = EvenOdd
+			case "nonzero":
+				state.fillRule = NonZero
+			}
+			stack = append(stack, state)
+
+			shape, err := svgShape(el.Name.Local, attrs)
+			if err != nil {
+				return Path{}, fmt.Errorf("%s: %w", el.Name.Local, err)
+			}
+			closed := Path{FillRule: state.fillRule}
+			for _, sp := range shape.Transform(state.transform).Subpaths {
+				if sp.IsClosed() {
+					closed.Subpaths = append(closed.Subpaths, sp)
+				} else {
+					result.Subpaths = append(result.Subpaths, sp)
+				}
+			}
+			if len(closed.Subpaths) > 0 {
+				result.Subpaths = append(result.Subpaths, closed.Union(Path{}).Subpaths...)
+			}
+		}
+	}
+	return result, nil
+}
+
+// svgAttributes collects an element's attributes with the declarations of
+// its style attribute, which take precedence as they do in a browser
+func svgAttributes(el xml.StartElement) map[string]string {
+	attrs := make(map[string]string, len(el.Attr))
+	for _, a := range el.Attr {
+		attrs[a.Name.Local] = strings.TrimSpace(a.Value)
+	}
+	for _, declaration := range strings.Split(attrs["style"], ";") {
+		name, value, ok := strings.Cut(declaration, ":")
+		if ok {
+			attrs[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return attrs
+}
+
+// svgViewport maps the root element's user units onto mm. A viewBox is
+// stretched over the width and height; without one a user unit is a CSS
+// pixel.
+func svgViewport(attrs map[string]string) (Transform, error) {
+	perUnit := svgUnits["px"]
+	box := strings.Fields(strings.ReplaceAll(attrs["viewBox"], ",", " "))
+	if len(box) != 4 {
+		return Scaling(perUnit, perUnit, Point{}), nil
+	}
+	var v [4]float64
+	for i, field := range box {
+		n, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return Transform{}, fmt.Errorf("invalid viewBox %q", attrs["viewBox"])
+		}
+		v[i] = n
+	}
+	if v[2] <= 0 || v[3] <= 0 {
+		return Transform{}, fmt.Errorf("invalid viewBox %q", attrs["viewBox"])
+	}
+
+	sx, sy := perUnit, perUnit
+	if width, ok, err := svgLength(attrs["width"]); err != nil {
+		return Transform{}, fmt.Errorf("width: %w", err)
+	} else if ok {
+		sx = width / v[2]
+	}
+	if height, ok, err := svgLength(attrs["height"]); err != nil {
+		return Transform{}, fmt.Errorf("height: %w", err)
+	} else if ok {
+		sy = height / v[3]
+	}
+	// Only one size given keeps the viewBox's proportions
+	if attrs["width"] == "" && attrs["height"] != "" {
+		sx = sy
+	} else if attrs["height"] == "" && attrs["width"] != "" {
+		sy = sx
+	}
+	return Translation(-v[0], -v[1]).Then(Scaling(sx, sy, Point{})), nil
+}
+
+// svgLength reads a length such as "210mm" in mm. Percentages and an empty
+// value report false.
+func svgLength(s string) (float64, bool, error) {
+	if s == "" || strings.HasSuffix(s, "%") {
+		return 0, false, nil
+	}
+	number := strings.TrimRight(s, "abcdefghijklmnopqrstuvwxyz")
+	unit, ok := svgUnits[s[len(number):]]
+	if !ok {
+		return 0, false, fmt.Errorf("unknown unit in %q", s)
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid length %q", s)
+	}
+	return v * unit, true, nil
+}
+
+// svgShape returns the outline of a shape element in its own user units.
+// Elements that draw nothing give an empty path.
+func svgShape(name string, attrs map[string]string) (Path, error) {
+	var values map[string]float64
+	numbers := func(names ...string) error {
+		values = make(map[string]float64, len(names))
+		for _, n := range names {
+			s := strings.TrimSuffix(attrs[n], "px")
+			if s == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", n, attrs[n])
+			}
+			values[n] = v
+		}
+		return nil
+	}
+
+	var sp Subpath
+	switch name {
+	case "path":
+		return ParsePath(attrs["d"])
+	case "rect":
+		if err := numbers("x", "y", "width", "height", "rx", "ry"); err != nil {
+			return Path{}, err
+		}
+		rx, hasRx := values["rx"]
+		ry, hasRy := values["ry"]
+		if !hasRx {
+			rx = ry
+		}
+		if !hasRy {
+			ry = rx
+		}
+		w, h := values["width"], values["height"]
+		if w <= 0 || h <= 0 {
+			return Path{}, nil
+		}
+		sp = svgRect(values["x"], values["y"], w, h, math.Min(rx, w/2), math.Min(ry, h/2))
+	case "circle":
+		if err := numbers("cx", "cy", "r"); err != nil {
+			return Path{}, err
+		}
+		if values["r"] <= 0 {
+			return Path{}, nil
+		}
+		sp = svgEllipse(values["cx"], values["cy"], values["r"], values["r"])
+	case "ellipse":
+		if err := numbers("cx", "cy", "rx", "ry"); err != nil {
+			return Path{}, err
+		}
+		if values["rx"] <= 0 || values["ry"] <= 0 {
+			return Path{}, nil
+		}
+		sp = svgEllipse(values["cx"], values["cy"], values["rx"], values["ry"])
+	case "line":
+		if err := numbers("x1", "y1", "x2", "y2"); err != nil {
+			return Path{}, err
+		}
+		sp = Subpath{
+			Start:    Point{values["x1"], values["y1"]},
+			Segments: []Segment{{Op: LineOp, End: Point{values["x2"], values["y2"]}}},
+		}
+	case "polyline", "polygon":
+		points, err := ParsePath("M" + attrs["points"])
+		if err != nil {
+			return Path{}, fmt.Errorf("invalid points: %w", err)
+		}
+		if len(points.Subpaths) == 0 {
+			return Path{}, nil
+		}
+		sp = points.Subpaths[0]
+		sp.Closed = name == "polygon"
+	default:
+		return Path{}, nil
+	}
+	return Path{Subpaths: []Subpath{sp}}, nil
+}
+
+// svgRect is a rectangle with elliptical corners, drawn the way SVG
+// defines it: clockwise from the end of the top left corner
+func svgRect(x, y, w, h, rx, ry float64) Subpath {
+	if rx <= 0 || ry <= 0 {
+		return Rect(x, y, w, h)
+	}
+	corner := func(end Point) Segment {
+		return Segment{Op: ArcOp, Radii: Point{rx, ry}, Sweep: true, End: end}
+	}
+	right, bottom := x+w, y+h
+	return Subpath{
+		Start: Point{x + rx, y},
+		Segments: []Segment{
+			{Op: LineOp, End: Point{right - rx, y}},
+			corner(Point{right, y + ry}),
+			{Op: LineOp, End: Point{right, bottom - ry}},
+			corner(Point{right - rx, bottom}),
+			{Op: LineOp, End: Point{x + rx, bottom}},
+			corner(Point{x, bottom - ry}),
+			{Op: LineOp, End: Point{x, y + ry}},
+			corner(Point{x + rx, y}),
+		},
+		Closed: true,
+	}
+}
+
+// svgEllipse is an ellipse drawn as two half arcs, clockwise from its
+// rightmost point
+func svgEllipse(cx, cy, rx, ry float64) Subpath {
+	half := func(end Point) Segment {
+		return Segment{Op: ArcOp, Radii: Point{rx, ry}, Sweep: true, End: end}
+	}
+	return Subpath{
+		Start:    Point{cx + rx, cy},
+		Segments: []Segment{half(Point{cx - rx, cy}), half(Point{cx + rx, cy})},
+		Closed:   true,
+	}
+}
+
+// parseTransform reads an SVG transform attribute. The transforms in the
+// list apply right to left, so the last one listed is applied first.
+func parseTransform(s string) (Transform, error) {
+	result := Identity()
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		open := strings.IndexByte(rest, '(')
+		end := strings.IndexByte(rest, ')')
+		if open < 0 || end < open {
+			return Transform{}, fmt.Errorf("invalid transform %q", s)
+		}
+		name := strings.TrimSpace(strings.Trim(rest[:open], " ,"))
+		inner := rest[open+1 : end]
+		var args []float64
+		for _, field := range tokenizePath(inner) {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return Transform{}, fmt.Errorf("invalid number %q in transform", field)
+			}
+			args = append(args, v)
+		}
+		rest = strings.TrimSpace(strings.TrimLeft(rest[end+1:], " ,"))
+
+		arg := func(i int, fallback float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return fallback
+		}
+		var t Transform
+		switch {
+		case name == "matrix" && len(args) == 6:
+			t = Transform{args[0], args[1], args[2], args[3], args[4], args[5]}
+		case name == "translate" && len(args) >= 1:
+			t = Translation(args[0], arg(1, 0))
+		case name == "scale" && len(args) >= 1:
+			t = Scaling(args[0], arg(1, args[0]), Point{})
+		case name == "rotate" && len(args) >= 1:
+			t = Rotation(args[0], Point{arg(1, 0), arg(2, 0)})
+		case name == "skewX" && len(args) == 1:
+			t = Transform{A: 1, C: math.Tan(args[0] * math.Pi / 180), D: 1}
+		case name == "skewY" && len(args) == 1:
+			t = Transform{A: 1, B: math.Tan(args[0] * math.Pi / 180), D: 1}
+		default:
+			return Transform{}, fmt.Errorf("unsupported transform %s(%s)", name, strings.TrimSpace(inner))
+		}
+		result = t.Then(result)
+	}
+	return result, nil
+}
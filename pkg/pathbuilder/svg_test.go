@@ -0,0 +1,34 @@
+package pathbuilder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSVGFillRulePerElement(t *testing.T) {
+	// Two overlapping circles, each filled by the even-odd rule, and a ring
+	// whose hole comes from the rule
+	doc := `<svg width="100mm" height="100mm" viewBox="0 0 100 100">
+		<circle cx="30" cy="30" r="20" fill-rule="evenodd"/>
+		<circle cx="50" cy="30" r="20" style="fill-rule:evenodd"/>
+		<g fill-rule="evenodd">
+			<path d="M10,80 h30 v15 h-30 z M20,85 h10 v5 h-10 z"/>
+		</g>
+	</svg>`
+	path, err := ParseSVG(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rings := path.Flatten(booleanTolerance)
+	inside := func(p Point) bool { return path.FillRule.contains(rings, p) }
+	for _, p := range []Point{{20, 30}, {40, 30}, {60, 30}, {15, 82}} {
+		if !inside(p) {
+			t.Errorf("%v should be filled", p)
+		}
+	}
+	for _, p := range []Point{{80, 30}, {25, 87}} {
+		if inside(p) {
+			t.Errorf("%v should not be filled", p)
+		}
+	}
+}
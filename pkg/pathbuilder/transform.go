@@ -0,0 +1,170 @@
+package pathbuilder
+
+import "math"
+
+// Transform is an affine map of the sheet with the coefficients of the SVG
+// matrix(a b c d e f): x' = A*x + C*y + E and y' = B*x + D*y + F. It lets a
+// feature be drawn once and then moved, turned, scaled or mirrored into
+// place.
+type Transform struct {
+	A, B, C, D, E, F float64
+}
+
+// Identity leaves every point where it is
+func Identity() Transform {
+	return Transform{A: 1, D: 1}
+}
+
+// Translation moves points by dx, dy
+func Translation(dx, dy float64) Transform {
+	return Transform{A: 1, D: 1, E: dx, F: dy}
+}
+
+// Rotation turns points about centre by degrees, clockwise on the sheet
+// like the SVG rotate transform
+func Rotation(degrees float64, centre Point) Transform {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	return Translation(-centre.X, -centre.Y).
+		Then(Transform{A: cos, B: sin, C: -sin, D: cos}).
+		Then(Translation(centre.X, centre.Y))
+}
+
+// Scaling stretches points away from centre by sx across and sy down
+func Scaling(sx, sy float64, centre Point) Transform {
+	return Translation(-centre.X, -centre.Y).
+		Then(Transform{A: sx, D: sy}).
+		Then(Translation(centre.X, centre.Y))
+}
+
+// MirrorX swaps left and right about the vertical line at x
+func MirrorX(x float64) Transform {
+	return Transform{A: -1, D: 1, E: 2 * x}
+}
+
+// MirrorY swaps top and bottom about the horizontal line at y
+func MirrorY(y float64) Transform {
+	return Transform{A: 1, D: -1, F: 2 * y}
+}
+
+// Then returns the transform that applies t and then u
+func (t Transform) Then(u Transform) Transform {
+	return Transform{
+		A: u.A*t.A + u.C*t.B,
+		B: u.B*t.A + u.D*t.B,
+		C: u.A*t.C + u.C*t.D,
+		D: u.B*t.C + u.D*t.D,
+		E: u.A*t.E + u.C*t.F + u.E,
+		F: u.B*t.E + u.D*t.F + u.F,
+	}
+}
+
+// Apply maps a point
+func (t Transform) Apply(p Point) Point {
+	return Point{t.A*p.X + t.C*p.Y + t.E, t.B*p.X + t.D*p.Y + t.F}
+}
+
+// Vector maps a direction or offset, which ignores the translation
+func (t Transform) Vector(v Point) Point {
+	return Point{t.A*v.X + t.C*v.Y, t.B*v.X + t.D*v.Y}
+}
+
+// mirrors reports whether the transform flips the sheet over, which turns
+// clockwise paths anticlockwise
+func (t Transform) mirrors() bool {
+	return t.A*t.D-t.B*t.C < 0
+}
+
+// Transform maps a segment drawn from p0. Curves map through their control
+// points; an arc's ellipse is mapped and its radii and rotation found
+// again, with the sweep flipped when the transform mirrors.
+func (s Segment) Transform(p0 Point, t Transform) Segment {
+	mapped := s
+	mapped.Ctrl = t.Apply(s.Ctrl)
+	mapped.Ctrl2 = t.Apply(s.Ctrl2)
+	mapped.End = t.Apply(s.End)
+	if s.Op != ArcOp {
+		return mapped
+	}
+
+	a, ok := arcCenter(p0, s)
+	if !ok {
+		return mapped
+	}
+	// The ellipse is the unit circle through N = T * R(phi) * diag(rx, ry);
+	// its axes are the eigenvectors of N * Nᵀ
+	axisX := t.Vector(Point{a.rx * math.Cos(a.phi), a.rx * math.Sin(a.phi)})
+	axisY := t.Vector(Point{-a.ry * math.Sin(a.phi), a.ry * math.Cos(a.phi)})
+	xx := axisX.X*axisX.X + axisY.X*axisY.X
+	xy := axisX.X*axisX.Y + axisY.X*axisY.Y
+	yy := axisX.Y*axisX.Y + axisY.Y*axisY.Y
+	mean := (xx + yy) / 2
+	spread := math.Hypot((xx-yy)/2, xy)
+	mapped.Radii = Point{math.Sqrt(mean + spread), math.Sqrt(math.Max(0, mean-spread))}
+	mapped.Rotation = math.Atan2(2*xy, xx-yy) / 2 * 180 / math.Pi
+	if t.mirrors() {
+		mapped.Sweep = !s.Sweep
+	}
+	return mapped
+}
+
+// Transform maps every point of the subpath
+func (sp Subpath) Transform(t Transform) Subpath {
+	mapped := Subpath{Start: t.Apply(sp.Start), Closed: sp.Closed}
+	pos := sp.Start
+	for _, segment := range sp.Segments {
+		mapped.Segments = append(mapped.Segments, segment.Transform(pos, t))
+		pos = segment.End
+	}
+	return mapped
+}
+
+// Transform maps every subpath of the path
+func (p Path) Transform(t Transform) Path {
+	mapped := Path{Subpaths: make([]Subpath, len(p.Subpaths)), FillRule: p.FillRule}
+	for i, sp := range p.Subpaths {
+		mapped.Subpaths[i] = sp.Transform(t)
+	}
+	return mapped
+}
+
+// Rotate turns the path about centre by degrees, clockwise on the sheet
+func (p Path) Rotate(degrees float64, centre Point) Path {
+	return p.Transform(Rotation(degrees, centre))
+}
+
+// Scale stretches the path away from centre
+func (p Path) Scale(sx, sy float64, centre Point) Path {
+	return p.Transform(Scaling(sx, sy, centre))
+}
+
+// MirrorX flips the path left to right about the vertical line at x
+func (p Path) MirrorX(x float64) Path {
+	return p.Transform(MirrorX(x))
+}
+
+// MirrorY flips the path top to bottom about the horizontal line at y
+func (p Path) MirrorY(y float64) Path {
+	return p.Transform(MirrorY(y))
+}
+
+// Fit scales the path evenly, so it keeps its proportions, until the curve
+// it draws just fits a width by height box at the origin, and centres it
+// there. An empty path or one with no area comes back unchanged.
+func (p Path) Fit(width, height float64) Path {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, polyline := range p.Flatten(booleanTolerance) {
+		for _, pt := range polyline {
+			minX, minY = math.Min(minX, pt.X), math.Min(minY, pt.Y)
+			maxX, maxY = math.Max(maxX, pt.X), math.Max(maxY, pt.Y)
+		}
+	}
+	w, h := maxX-minX, maxY-minY
+	if len(p.Subpaths) == 0 || w <= 0 || h <= 0 {
+		return p
+	}
+	s := math.Min(width/w, height/h)
+	return p.Transform(Translation(-(minX+maxX)/2, -(minY+maxY)/2).
+		Then(Scaling(s, s, Point{})).
+		Then(Translation(width/2, height/2)))
+}
@@ -0,0 +1,277 @@
+package toolpath
+
+import (
+	"math"
+	"sort"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// cleanupTolerance is how close in mm lines must be to count as the same
+const cleanupTolerance = 0.01
+
+// priority decides which of two lines running along each other is
+// machined: cutting through a line leaves nothing to crease or perforate
+func priority(kind box.LineKind) int {
+	switch kind {
+	case box.Cut:
+		return 2
+	case box.Perforation:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Overlap is the length of a layer dropped because a line that wins over
+// it, or an earlier line of the same kind, already runs there
+type Overlap struct {
+	Layer     string
+	Kind      box.LineKind
+	Under     string
+	UnderKind box.LineKind
+	Length    float64
+}
+
+// Report records what Clean changed
+type Report struct {
+	// Empty counts segments of zero length that were removed
+	Empty int
+	// Merged counts straight segments joined onto the one before
+	Merged int
+	// Overlaps lists the stretches dropped, one entry per pair of layers
+	Overlaps []Overlap
+}
+
+// Changed reports whether Clean did anything
+func (r Report) Changed() bool {
+	return r.Empty > 0 || r.Merged > 0 || len(r.Overlaps) > 0
+}
+
+// Clean tidies strokes before they are ordered. Zero-length segments are
+// removed and straight segments that carry on in line are merged. Where
+// straight lines of different strokes run along each other the line kind
+// with the higher priority keeps the stretch (a cut wins over a
+// perforation, which wins over a crease) and a second line of the same kind
+// is dropped, so the machine never goes over the same line twice. Strokes
+// lose only the overlapping stretches and are split around them.
+// Annotations are left as they are.
+func Clean(strokes []Stroke) ([]Stroke, Report) {
+	var report Report
+	cleaned := make([]Stroke, len(strokes))
+	for i, s := range strokes {
+		cleaned[i] = s
+		if s.Kind == box.Annotation {
+			continue
+		}
+		count := len(s.Path.Segments)
+		path := s.Path.DropEmpty(cleanupTolerance)
+		report.Empty += count - len(path.Segments)
+		cleaned[i].Path = path.MergeLines()
+		report.Merged += len(path.Segments) - len(cleaned[i].Path.Segments)
+	}
+
+	// Strokes that win overlaps are settled first
+	order := make([]int, 0, len(cleaned))
+	for i, s := range cleaned {
+		if s.Kind != box.Annotation && len(s.Path.Segments) > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return priority(cleaned[order[a]].Kind) > priority(cleaned[order[b]].Kind)
+	})
+
+	pieces := make([][]pathbuilder.Subpath, len(cleaned))
+	overlaps := map[[2]string]*Overlap{}
+	var settled []line
+	for _, i := range order {
+		s := cleaned[i]
+		var removed map[int]float64
+		pieces[i], removed = trim(s.Path, settled)
+		for w, length := range removed {
+			key := [2]string{s.Layer, settled[w].layer}
+			if overlaps[key] == nil {
+				overlaps[key] = &Overlap{Layer: s.Layer, Kind: s.Kind, Under: settled[w].layer, UnderKind: settled[w].kind}
+			}
+			overlaps[key].Length += length
+		}
+		for _, piece := range pieces[i] {
+			settled = append(settled, lines(piece, s)...)
+		}
+	}
+
+	for _, o := range overlaps {
+		report.Overlaps = append(report.Overlaps, *o)
+	}
+	sort.Slice(report.Overlaps, func(a, b int) bool {
+		oa, ob := report.Overlaps[a], report.Overlaps[b]
+		if oa.Layer != ob.Layer {
+			return oa.Layer < ob.Layer
+		}
+		return oa.Under < ob.Under
+	})
+
+	var result []Stroke
+	for i, s := range cleaned {
+		if s.Kind == box.Annotation {
+			result = append(result, s)
+			continue
+		}
+		for _, piece := range pieces[i] {
+			result = append(result, Stroke{Layer: s.Layer, Kind: s.Kind, Path: piece})
+		}
+	}
+	return result, report
+}
+
+// line is a straight segment of a settled stroke
+type line struct {
+	a, b  pathbuilder.Point
+	layer string
+	kind  box.LineKind
+}
+
+// lines lists the straight segments of a subpath, including the one a Z
+// draws back to the start
+func lines(sp pathbuilder.Subpath, s Stroke) []line {
+	var result []line
+	pos := sp.Start
+	for _, segment := range closedSegments(sp) {
+		if segment.Op == pathbuilder.LineOp {
+			result = append(result, line{a: pos, b: segment.End, layer: s.Layer, kind: s.Kind})
+		}
+		pos = segment.End
+	}
+	return result
+}
+
+// closedSegments spells out the line a Z draws back to the start
+func closedSegments(sp pathbuilder.Subpath) []pathbuilder.Segment {
+	if sp.Closed && sp.End().Dist(sp.Start) > 0 {
+		return append(append([]pathbuilder.Segment{}, sp.Segments...), pathbuilder.Segment{Op: pathbuilder.LineOp, End: sp.Start})
+	}
+	return sp.Segments
+}
+
+// trim removes the stretches of a subpath's straight segments that settled
+// lines already cover and returns the pieces left, with the length taken
+// away under each settled line. A subpath that loses nothing comes back
+// whole; a closed one that loses something is opened at the gap.
+func trim(sp pathbuilder.Subpath, settled []line) ([]pathbuilder.Subpath, map[int]float64) {
+	removed := map[int]float64{}
+	var pieces []pathbuilder.Subpath
+	current := pathbuilder.Subpath{Start: sp.Start}
+	draw := func(from pathbuilder.Point, segment pathbuilder.Segment) {
+		if current.End().Dist(from) > cleanupTolerance {
+			if len(current.Segments) > 0 {
+				pieces = append(pieces, current)
+			}
+			current = pathbuilder.Subpath{Start: from}
+		}
+		current.Segments = append(current.Segments, segment)
+	}
+
+	pos := sp.Start
+	for _, segment := range closedSegments(sp) {
+		if segment.Op != pathbuilder.LineOp {
+			draw(pos, segment)
+			pos = segment.End
+			continue
+		}
+		length := pos.Dist(segment.End)
+		keep := []span{{0, 1}}
+		for w, l := range settled {
+			u0, u1, ok := overlap(pos, segment.End, l.a, l.b)
+			if !ok {
+				continue
+			}
+			var taken float64
+			keep, taken = subtract(keep, u0, u1, cleanupTolerance/length)
+			if taken > 0 {
+				removed[w] += taken * length
+			}
+		}
+		for _, k := range keep {
+			from, to := interpolate(pos, segment.End, k.from), interpolate(pos, segment.End, k.to)
+			draw(from, pathbuilder.Segment{Op: pathbuilder.LineOp, End: to})
+		}
+		pos = segment.End
+	}
+	if len(current.Segments) > 0 {
+		pieces = append(pieces, current)
+	}
+
+	if len(removed) == 0 {
+		return []pathbuilder.Subpath{sp}, removed
+	}
+	// The piece that finishes a closed subpath carries on into the one that
+	// starts it
+	if last := len(pieces) - 1; sp.Closed && last > 0 &&
+		pieces[0].Start.Dist(sp.Start) <= cleanupTolerance && pieces[last].End().Dist(sp.Start) <= cleanupTolerance {
+		pieces[last].Segments = append(pieces[last].Segments, pieces[0].Segments...)
+		pieces = pieces[1:]
+	}
+	for i := range pieces {
+		pieces[i] = pieces[i].MergeLines()
+	}
+	return pieces, removed
+}
+
+// span is a stretch of a line between two fractions of its length
+type span struct{ from, to float64 }
+
+// subtract removes u0 to u1 from the spans, dropping slivers shorter than
+// minimum, and returns how much was removed
+func subtract(spans []span, u0, u1, minimum float64) ([]span, float64) {
+	var result []span
+	taken := 0.0
+	for _, s := range spans {
+		lo, hi := math.Max(s.from, u0), math.Min(s.to, u1)
+		if hi <= lo {
+			result = append(result, s)
+			continue
+		}
+		taken += hi - lo
+		if lo-s.from > minimum {
+			result = append(result, span{s.from, lo})
+		}
+		if s.to-hi > minimum {
+			result = append(result, span{hi, s.to})
+		}
+	}
+	return result, taken
+}
+
+// overlap finds the stretch of the line p to q, as fractions of its length,
+// that the line a to b runs along
+func overlap(p, q, a, b pathbuilder.Point) (float64, float64, bool) {
+	dx, dy := q.X-p.X, q.Y-p.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return 0, 0, false
+	}
+	offset := func(pt pathbuilder.Point) float64 {
+		return math.Abs(dx*(pt.Y-p.Y)-dy*(pt.X-p.X)) / length
+	}
+	if offset(a) > cleanupTolerance || offset(b) > cleanupTolerance {
+		return 0, 0, false
+	}
+	along := func(pt pathbuilder.Point) float64 {
+		return (dx*(pt.X-p.X) + dy*(pt.Y-p.Y)) / (length * length)
+	}
+	u0, u1 := along(a), along(b)
+	if u0 > u1 {
+		u0, u1 = u1, u0
+	}
+	u0, u1 = math.Max(u0, 0), math.Min(u1, 1)
+	if (u1-u0)*length <= cleanupTolerance {
+		return 0, 0, false
+	}
+	return u0, u1, true
+}
+
+func interpolate(a, b pathbuilder.Point, t float64) pathbuilder.Point {
+	return pathbuilder.Point{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}
+}
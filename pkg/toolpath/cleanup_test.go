@@ -0,0 +1,144 @@
+package toolpath
+
+import (
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// stroke returns a stroke on a layer through the points
+func stroke(layer string, points ...pathbuilder.Point) Stroke {
+	sp := pathbuilder.Subpath{Start: points[0]}
+	for _, p := range points[1:] {
+		sp.Segments = append(sp.Segments, pathbuilder.Segment{Op: pathbuilder.LineOp, End: p})
+	}
+	return Stroke{Layer: layer, Kind: box.LayerKind(layer), Path: sp}
+}
+
+// paths renders the strokes of a layer
+func paths(strokes []Stroke, layer string) []string {
+	var result []string
+	for _, s := range strokes {
+		if s.Layer == layer {
+			result = append(result, s.Path.String())
+		}
+	}
+	return result
+}
+
+func TestCleanSimplifiesStrokes(t *testing.T) {
+	strokes := []Stroke{
+		stroke("cut_lines", pathbuilder.Point{X: 0, Y: 0}, pathbuilder.Point{X: 0, Y: 0},
+			pathbuilder.Point{X: 5, Y: 0}, pathbuilder.Point{X: 10, Y: 0}, pathbuilder.Point{X: 10, Y: 10}),
+		stroke("note_label", pathbuilder.Point{X: 0, Y: 20}, pathbuilder.Point{X: 0, Y: 20}),
+	}
+	cleaned, report := Clean(strokes)
+	if got := paths(cleaned, "cut_lines"); len(got) != 1 || got[0] != "M0,0L10,0L10,10" {
+		t.Errorf("got cut %v, want M0,0L10,0L10,10", got)
+	}
+	if got := paths(cleaned, "note_label"); len(got) != 1 || got[0] != "M0,20L0,20" {
+		t.Errorf("annotations should be left alone, got %v", got)
+	}
+	if report.Empty != 1 || report.Merged != 1 || len(report.Overlaps) != 0 {
+		t.Errorf("got report %+v, want 1 empty and 1 merged segment", report)
+	}
+}
+
+func TestCleanOverlapPriority(t *testing.T) {
+	strokes := []Stroke{
+		stroke("fold_lines", pathbuilder.Point{X: 0, Y: 0}, pathbuilder.Point{X: 30, Y: 0}),
+		stroke("perf_tear", pathbuilder.Point{X: 25, Y: 0}, pathbuilder.Point{X: 15, Y: 0}),
+		stroke("cut_lines", pathbuilder.Point{X: 5, Y: 0}, pathbuilder.Point{X: 15, Y: 0}),
+		stroke("cut_more", pathbuilder.Point{X: 10, Y: 0}, pathbuilder.Point{X: 20, Y: 0}),
+	}
+	cleaned, report := Clean(strokes)
+
+	// The cut keeps its line, the second cut loses the part already cut,
+	// the perforation loses that too and the crease keeps only its ends
+	want := map[string][]string{
+		"cut_lines":  {"M5,0L15,0"},
+		"cut_more":   {"M15,0L20,0"},
+		"perf_tear":  {"M25,0L20,0"},
+		"fold_lines": {"M0,0L5,0", "M25,0L30,0"},
+	}
+	for layer, w := range want {
+		got := paths(cleaned, layer)
+		if len(got) != len(w) {
+			t.Errorf("%s: got %v, want %v", layer, got, w)
+			continue
+		}
+		for i := range w {
+			if got[i] != w[i] {
+				t.Errorf("%s: got %v, want %v", layer, got, w)
+			}
+		}
+	}
+
+	wantOverlaps := []Overlap{
+		{Layer: "cut_more", Kind: box.Cut, Under: "cut_lines", UnderKind: box.Cut, Length: 5},
+		{Layer: "fold_lines", Kind: box.Crease, Under: "cut_lines", UnderKind: box.Cut, Length: 10},
+		{Layer: "fold_lines", Kind: box.Crease, Under: "cut_more", UnderKind: box.Cut, Length: 5},
+		{Layer: "fold_lines", Kind: box.Crease, Under: "perf_tear", UnderKind: box.Perforation, Length: 5},
+		{Layer: "perf_tear", Kind: box.Perforation, Under: "cut_more", UnderKind: box.Cut, Length: 5},
+	}
+	if len(report.Overlaps) != len(wantOverlaps) {
+		t.Fatalf("got overlaps %+v, want %+v", report.Overlaps, wantOverlaps)
+	}
+	for i, o := range report.Overlaps {
+		w := wantOverlaps[i]
+		if o.Layer != w.Layer || o.Under != w.Under || o.Kind != w.Kind || o.UnderKind != w.UnderKind || o.Length < w.Length-1e-9 || o.Length > w.Length+1e-9 {
+			t.Errorf("overlap %d: got %+v, want %+v", i, o, w)
+		}
+	}
+}
+
+func TestCleanDropsFoldsUnderBoxCuts(t *testing.T) {
+	b := *box.NewBox(100, 40, 120, 0)
+	layers, err := b.GenerateCompleteBox()
+	if err != nil {
+		t.Fatal(err)
+	}
+	strokes, err := FromLayers(layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleaned, report := Clean(strokes)
+
+	// The fold rectangle from GenerateFoldLines runs along the top edges
+	// the tuck closure cuts
+	dropped := false
+	for _, o := range report.Overlaps {
+		if o.Layer == "fold_lines" && o.UnderKind == box.Cut {
+			dropped = true
+		}
+	}
+	if !dropped {
+		t.Errorf("no fold lines were dropped under cuts: %+v", report.Overlaps)
+	}
+
+	// Nothing left is cut twice, or cut and then creased
+	var cuts []line
+	for _, s := range cleaned {
+		if s.Kind == box.Cut {
+			cuts = append(cuts, lines(s.Path, s)...)
+		}
+	}
+	for _, s := range cleaned {
+		if s.Kind == box.Annotation {
+			continue
+		}
+		pos := s.Path.Start
+		for _, segment := range closedSegments(s.Path) {
+			for _, c := range cuts {
+				if c.layer == s.Layer && c.a == pos && c.b == segment.End {
+					continue
+				}
+				if _, _, ok := overlap(pos, segment.End, c.a, c.b); ok {
+					t.Errorf("%s from %v to %v runs along the cut %s from %v to %v", s.Layer, pos, segment.End, c.layer, c.a, c.b)
+				}
+			}
+			pos = segment.End
+		}
+	}
+}
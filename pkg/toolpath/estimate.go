@@ -0,0 +1,115 @@
+package toolpath
+
+import (
+	"math"
+	"time"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Profile describes how a machine moves, for estimating how long a job
+// takes. Speeds are in mm/s.
+type Profile struct {
+	// Speeds is the feed with the tool down, by line kind
+	Speeds map[box.LineKind]float64
+	// TravelSpeed is the feed with the tool up between strokes
+	TravelSpeed float64
+	// Acceleration in mm/s² is used both to speed up and to slow down; zero
+	// treats every move as starting at full speed
+	Acceleration float64
+	// PierceTime is how long lowering the tool into the board takes, in
+	// seconds, paid once at the start of every stroke
+	PierceTime float64
+	// CornerAngle is the turn in degrees above which the tool stops at a
+	// corner; gentler turns are taken at speed
+	CornerAngle float64
+	// Tolerance is the maximum chord error when curves are flattened
+	Tolerance float64
+}
+
+// DefaultProfile is a typical flatbed cutter with a tangential knife
+func DefaultProfile() Profile {
+	return Profile{
+		Speeds: map[box.LineKind]float64{
+			box.Cut:         25,
+			box.Crease:      40,
+			box.Perforation: 15,
+		},
+		TravelSpeed:  200,
+		Acceleration: 1000,
+		PierceTime:   0.3,
+		CornerAngle:  30,
+		Tolerance:    0.1,
+	}
+}
+
+// Lengths adds up the tool-down length of each line kind. Annotations are
+// not machined and are left out.
+func Lengths(strokes []Stroke) map[box.LineKind]float64 {
+	lengths := make(map[box.LineKind]float64)
+	for _, s := range strokes {
+		if s.Kind != box.Annotation {
+			lengths[s.Kind] += s.Path.Length()
+		}
+	}
+	return lengths
+}
+
+// Estimate returns how long the machine takes to work through the plan from
+// origin: every stroke at its kind's speed, stopping at sharp corners, plus
+// the pierce at its start and the travel to reach it.
+func (plan Plan) Estimate(origin pathbuilder.Point, profile Profile) time.Duration {
+	seconds := 0.0
+	pos := origin
+	for _, s := range plan.Strokes {
+		seconds += profile.move(pos.Dist(s.Start()), profile.TravelSpeed)
+		seconds += profile.PierceTime
+		for _, run := range profile.runs(s.Path) {
+			seconds += profile.move(run, profile.Speeds[s.Kind])
+		}
+		pos = s.End()
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// runs splits a stroke at the corners the tool stops at and returns the
+// length of each piece
+func (profile Profile) runs(sp pathbuilder.Subpath) []float64 {
+	points := sp.Flatten(profile.Tolerance)
+	limit := profile.CornerAngle * math.Pi / 180
+	var runs []float64
+	run := 0.0
+	for i := 1; i < len(points); i++ {
+		run += points[i-1].Dist(points[i])
+		if i+1 < len(points) && turn(points[i-1], points[i], points[i+1]) > limit {
+			runs = append(runs, run)
+			run = 0
+		}
+	}
+	return append(runs, run)
+}
+
+// move is the time to cover a distance from standstill to standstill,
+// speeding up to the given speed if there is room to reach it
+func (profile Profile) move(distance, speed float64) float64 {
+	if distance <= 0 || speed <= 0 {
+		return 0
+	}
+	a := profile.Acceleration
+	if a <= 0 {
+		return distance / speed
+	}
+	if distance < speed*speed/a {
+		// Triangular profile: never reaches full speed
+		return 2 * math.Sqrt(distance/a)
+	}
+	return distance/speed + speed/a
+}
+
+// turn is the change of direction in radians at b
+func turn(a, b, c pathbuilder.Point) float64 {
+	inX, inY := b.X-a.X, b.Y-a.Y
+	outX, outY := c.X-b.X, c.Y-b.Y
+	return math.Abs(math.Atan2(inX*outY-inY*outX, inX*outX+inY*outY))
+}
@@ -0,0 +1,259 @@
+package toolpath
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+// Stroke is one continuous tool-down run taken from a box layer
+type Stroke struct {
+	Layer string
+	Kind  box.LineKind
+	Path  pathbuilder.Subpath
+}
+
+// Start returns where the tool goes down
+func (s Stroke) Start() pathbuilder.Point {
+	return s.Path.Start
+}
+
+// End returns where the tool comes up
+func (s Stroke) End() pathbuilder.Point {
+	return s.Path.End()
+}
+
+// Plan is an ordered list of strokes ready for a writer
+type Plan struct {
+	Strokes []Stroke
+	// Travel is the pen-up distance from the origin through every stroke
+	Travel float64
+}
+
+// FromLayers splits the paths from GenerateCompleteBox into strokes.
+// Layers are read in name order so the result doesn't depend on map order.
+func FromLayers(layers map[string]string) ([]Stroke, error) {
+	names := make([]string, 0, len(layers))
+	for name := range layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var strokes []Stroke
+	for _, name := range names {
+		path, err := pathbuilder.ParsePath(layers[name])
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", name, err)
+		}
+		for _, sp := range path.Subpaths {
+			strokes = append(strokes, Stroke{
+				Layer: name,
+				Kind:  box.LayerKind(name),
+				Path:  sp,
+			})
+		}
+	}
+	return strokes, nil
+}
+
+// Order sorts strokes for cutting: creases first while the sheet is still
+// whole, perforations next while it is still held in one piece, then cuts
+// from the innermost features out so the outer contour is cut last. Within
+// each group the next stroke is the one closest to the tool, reversing open
+// strokes and re-entering closed ones at their nearest vertex. Annotations
+// are not machined and are left out of the plan.
+func Order(strokes []Stroke, origin pathbuilder.Point) Plan {
+	var creases, perforations, cuts []Stroke
+	for _, s := range strokes {
+		switch s.Kind {
+		case box.Crease:
+			creases = append(creases, s)
+		case box.Perforation:
+			perforations = append(perforations, s)
+		case box.Cut:
+			cuts = append(cuts, s)
+		}
+	}
+
+	// Group cuts by how many closed cuts and contours surround them, deepest
+	// first
+	depths := containmentDepths(cuts)
+	byDepth := map[int][]Stroke{}
+	maxDepth := 0
+	for i, s := range cuts {
+		byDepth[depths[i]] = append(byDepth[depths[i]], s)
+		maxDepth = max(maxDepth, depths[i])
+	}
+
+	groups := [][]Stroke{creases, perforations}
+	for depth := maxDepth; depth >= 0; depth-- {
+		groups = append(groups, byDepth[depth])
+	}
+
+	plan := Plan{}
+	pos := origin
+	for _, group := range groups {
+		remaining := append([]Stroke{}, group...)
+		for len(remaining) > 0 {
+			index, next := nearest(pos, remaining)
+			plan.Travel += pos.Dist(next.Start())
+			plan.Strokes = append(plan.Strokes, next)
+			pos = next.End()
+			remaining = append(remaining[:index], remaining[index+1:]...)
+		}
+	}
+	return plan
+}
+
+// nearest finds the stroke, and the orientation of it, that starts closest to pos
+func nearest(pos pathbuilder.Point, strokes []Stroke) (int, Stroke) {
+	bestIndex := 0
+	bestDist := math.Inf(1)
+	var best Stroke
+	for i, s := range strokes {
+		for _, candidate := range orientations(s) {
+			if d := pos.Dist(candidate.Start()); d < bestDist {
+				bestIndex, bestDist, best = i, d, candidate
+			}
+		}
+	}
+	return bestIndex, best
+}
+
+// orientations lists the ways a stroke can be cut. Closed strokes keep their
+// direction but may start at any vertex; open strokes may be reversed.
+func orientations(s Stroke) []Stroke {
+	if s.Path.IsClosed() {
+		var options []Stroke
+		for i := range s.Path.Vertices() {
+			rotated := s
+			rotated.Path = s.Path.RotateTo(i)
+			options = append(options, rotated)
+		}
+		return options
+	}
+	reversed := s
+	reversed.Path = s.Path.Reverse()
+	return []Stroke{s, reversed}
+}
+
+// joinTolerance is how close in mm an open stroke must come to another to
+// count as part of the same contour
+const joinTolerance = 0.05
+
+// contour is a group of open strokes that meet, such as a part's outer
+// contour drawn by several layers
+type contour struct {
+	minX, minY, maxX, maxY float64
+	members                map[int]bool
+}
+
+// containmentDepths counts for each stroke how many closed strokes, and
+// contours of open strokes, have bounds enclosing it. The strokes forming a
+// contour are not counted as inside it, so holes and slits inside a part
+// come before the open cuts that free it.
+func containmentDepths(strokes []Stroke) []int {
+	containers := contours(strokes)
+	for i, s := range strokes {
+		if s.Path.IsClosed() {
+			c := contour{members: map[int]bool{i: true}}
+			c.minX, c.minY, c.maxX, c.maxY = s.Path.Bounds()
+			containers = append(containers, c)
+		}
+	}
+	depths := make([]int, len(strokes))
+	for i, inner := range strokes {
+		ix0, iy0, ix1, iy1 := inner.Path.Bounds()
+		for _, outer := range containers {
+			if outer.members[i] {
+				continue
+			}
+			ox0, oy0, ox1, oy1 := outer.minX, outer.minY, outer.maxX, outer.maxY
+			contains := ox0 <= ix0 && oy0 <= iy0 && ox1 >= ix1 && oy1 >= iy1
+			same := ox0 == ix0 && oy0 == iy0 && ox1 == ix1 && oy1 == iy1
+			if contains && !same {
+				depths[i]++
+			}
+		}
+	}
+	return depths
+}
+
+// contours groups open strokes that end on one another. A lone open stroke,
+// such as a slit, bounds nothing and is not a contour.
+func contours(strokes []Stroke) []contour {
+	var open []int
+	lines := map[int][]pathbuilder.Point{}
+	for i, s := range strokes {
+		if !s.Path.IsClosed() && len(s.Path.Segments) > 0 {
+			open = append(open, i)
+			lines[i] = s.Path.Flatten(joinTolerance)
+		}
+	}
+
+	// Union-find over the open strokes
+	parent := map[int]int{}
+	var root func(i int) int
+	root = func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+		return i
+	}
+	for _, i := range open {
+		parent[i] = i
+	}
+	for _, i := range open {
+		for _, j := range open {
+			if i == j || root(i) == root(j) {
+				continue
+			}
+			end := strokes[i]
+			if touches(lines[j], end.Start()) || touches(lines[j], end.End()) {
+				parent[root(i)] = root(j)
+			}
+		}
+	}
+
+	groups := map[int]*contour{}
+	var order []int
+	for _, i := range open {
+		r := root(i)
+		x0, y0, x1, y1 := strokes[i].Path.Bounds()
+		c, ok := groups[r]
+		if !ok {
+			c = &contour{minX: x0, minY: y0, maxX: x1, maxY: y1, members: map[int]bool{}}
+			groups[r] = c
+			order = append(order, r)
+		}
+		c.members[i] = true
+		c.minX, c.minY = math.Min(c.minX, x0), math.Min(c.minY, y0)
+		c.maxX, c.maxY = math.Max(c.maxX, x1), math.Max(c.maxY, y1)
+	}
+	var result []contour
+	for _, r := range order {
+		if len(groups[r].members) > 1 {
+			result = append(result, *groups[r])
+		}
+	}
+	return result
+}
+
+// touches reports whether a point lies on a polyline
+func touches(line []pathbuilder.Point, p pathbuilder.Point) bool {
+	for k := 0; k+1 < len(line); k++ {
+		a, b := line[k], line[k+1]
+		dx, dy := b.X-a.X, b.Y-a.Y
+		t := 0.0
+		if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+			t = math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/lengthSq))
+		}
+		if p.Dist(pathbuilder.Point{X: a.X + dx*t, Y: a.Y + dy*t}) <= joinTolerance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package toolpath
+
+import (
+	"strings"
+	"testing"
+
+	"42clients.com/puzzlebox/pkg/box"
+	"42clients.com/puzzlebox/pkg/pathbuilder"
+)
+
+func TestOrderCutsHolesBeforeContour(t *testing.T) {
+	b := *box.NewBox(100, 70, 120, 0)
+	b.Windows = []box.Window{{Panel: box.Front, Shape: box.WindowRect, X: 50, Y: 60, Width: 30, Height: 30}}
+	hanger := box.DefaultHanger()
+	b.Hanger = &hanger
+	if err := (box.TuckEnd{}).Validate(b); err != nil {
+		t.Fatal(err)
+	}
+	layers, err := b.GenerateCompleteBox()
+	if err != nil {
+		t.Fatal(err)
+	}
+	strokes, err := FromLayers(layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strokes, _ = Clean(strokes)
+	plan := Order(strokes, pathbuilder.Point{})
+
+	lastHole, firstContour := -1, len(plan.Strokes)
+	seenCut := false
+	for i, s := range plan.Strokes {
+		switch {
+		case s.Kind == box.Cut:
+			seenCut = true
+		case seenCut:
+			t.Errorf("%s stroke %d comes after a cut", s.Kind, i)
+		}
+		if strings.HasPrefix(s.Layer, "cut_hole_") {
+			lastHole = i
+		} else if s.Kind == box.Cut && !s.Path.IsClosed() {
+			firstContour = min(firstContour, i)
+		}
+	}
+	if lastHole < 0 {
+		t.Fatal("no holes were planned")
+	}
+	if lastHole > firstContour {
+		t.Errorf("hole at stroke %d is cut after the contour starts at stroke %d", lastHole, firstContour)
+	}
+}
+
+func TestOrderNestedClosedCuts(t *testing.T) {
+	square := func(layer string, x, size float64) Stroke {
+		return Stroke{Layer: layer, Kind: box.Cut, Path: pathbuilder.Rect(x, x, size, size)}
+	}
+	strokes := []Stroke{
+		square("cut_outer", 0, 100),
+		square("cut_middle", 10, 80),
+		square("cut_inner", 20, 60),
+		{Layer: "fold_crease", Kind: box.Crease, Path: pathbuilder.Subpath{
+			Start:    pathbuilder.Point{X: 0, Y: 50},
+			Segments: []pathbuilder.Segment{{Op: pathbuilder.LineOp, End: pathbuilder.Point{X: 100, Y: 50}}},
+		}},
+		{Layer: "note_label", Kind: box.Annotation, Path: pathbuilder.Rect(0, 0, 5, 5)},
+	}
+	plan := Order(strokes, pathbuilder.Point{})
+	var got []string
+	for _, s := range plan.Strokes {
+		got = append(got, s.Layer)
+	}
+	want := []string{"fold_crease", "cut_inner", "cut_middle", "cut_outer"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got order %v, want %v", got, want)
+	}
+}